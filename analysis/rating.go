@@ -0,0 +1,49 @@
+package analysis
+
+import "r6-replay-recorder/models"
+
+// SiegeScope Rating weights. A round starts at RatingBase and each stat adds
+// its weight per occurrence, so an average round comes out close to 1.0.
+// The same table is documented in the README; keep the two in sync.
+const (
+	RatingBase       = 0.70
+	RatingKill       = 0.45
+	RatingDeath      = -0.30
+	RatingAssist     = 0.15
+	RatingEntryKill  = 0.20
+	RatingEntryDeath = -0.15
+	RatingKOST       = 0.25
+	RatingClutchWin  = 0.50
+	RatingObjective  = 0.20 // planted or disabled the defuser
+	RatingMultiKill  = 0.10 // per double, triple and quad kill, so a triple earns it twice
+	RatingAce        = 0.20
+)
+
+// Rating returns the SiegeScope Rating of one player's round
+func Rating(s *models.PlayerRoundStats) float64 {
+	r := RatingBase +
+		RatingKill*float64(s.Kills) +
+		RatingAssist*float64(s.Assists) +
+		RatingClutchWin*float64(s.ClutchWins) +
+		RatingMultiKill*float64(s.DoubleKills+s.TripleKills+s.QuadKills)
+
+	if s.Died {
+		r += RatingDeath
+	}
+	if s.EntryKill {
+		r += RatingEntryKill
+	}
+	if s.EntryDeath {
+		r += RatingEntryDeath
+	}
+	if s.KOST {
+		r += RatingKOST
+	}
+	if s.DefuserPlants > 0 || s.DefuserDefuses > 0 {
+		r += RatingObjective
+	}
+	if s.Ace {
+		r += RatingAce
+	}
+	return r
+}
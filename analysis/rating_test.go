@@ -0,0 +1,53 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"r6-replay-recorder/models"
+)
+
+func TestRating(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats models.PlayerRoundStats
+		want  float64
+	}{
+		{
+			name:  "a round with nothing in it is the base",
+			stats: models.PlayerRoundStats{},
+			want:  0.70,
+		},
+		{
+			name:  "lost the opening duel",
+			stats: models.PlayerRoundStats{Died: true, EntryDeath: true},
+			want:  0.70 - 0.30 - 0.15,
+		},
+		{
+			// 0.70 base + 2 x 0.45 kills - 0.30 death + 0.15 assist + 0.20 entry kill
+			// + 0.25 KOST + 0.20 plant + 0.10 double kill
+			name: "opening kill, plant and a double",
+			stats: models.PlayerRoundStats{
+				Kills: 2, Died: true, Assists: 1, EntryKill: true, KOST: true,
+				DefuserPlants: 1, DoubleKills: 1,
+			},
+			want: 2.20,
+		},
+		{
+			// 0.70 base + 5 x 0.45 kills + 0.25 KOST + 0.50 clutch + 0.10 quad + 0.20 ace
+			name: "ace in a won clutch",
+			stats: models.PlayerRoundStats{
+				Kills: 5, KOST: true, ClutchWins: 1, QuadKills: 1, Ace: true,
+			},
+			want: 4.00,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Rating(&tt.stats); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Rating() = %.4f, want %.4f", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+// Package analysis holds the round analysis that has to give the same answer
+// whether it runs on a freshly decoded replay or on events already stored in
+// the database, so both the parser and schema migrations can use it.
+package analysis
+
+// Round timing. The in-game timer counts down from ActionPhaseSeconds and,
+// once the defuser is planted, restarts at DefuserSeconds.
+const (
+	ActionPhaseSeconds = 180.0
+	DefuserSeconds     = 45.0
+)
+
+// Default analysis windows, used until they are changed in settings
+const (
+	TradeWindowSeconds     = 3.0
+	MultiKillWindowSeconds = 10.0
+)
+
+// Event is a single feed entry. Type is the r6-dissect MatchUpdateType name
+// ("Kill", "Death", "DefuserPlantComplete", ...) and Time the countdown timer
+// reading recorded with it.
+type Event struct {
+	Type     string
+	Time     float64
+	Username string
+	Target   string
+}
+
+// Clock turns countdown timer readings into seconds since the action phase
+// started. Events must be fed in feed order.
+type Clock struct {
+	planted   bool
+	plantedAt float64
+	last      float64
+}
+
+// timed lists the events whose timer reading is from the action phase.
+// Operator swaps and objective callouts happen during the preparation phase,
+// and players leaving are often logged late, so those are placed at the last
+// timed event instead.
+var timed = map[string]bool{
+	"Kill":                   true,
+	"Death":                  true,
+	"DefuserPlantStart":      true,
+	"DefuserPlantComplete":   true,
+	"DefuserDisableStart":    true,
+	"DefuserDisableComplete": true,
+}
+
+// Tick returns the elapsed time of an event
+func (c *Clock) Tick(e Event) float64 {
+	if !timed[e.Type] {
+		return c.last
+	}
+
+	var elapsed float64
+	if c.planted {
+		elapsed = c.plantedAt + DefuserSeconds - e.Time
+	} else {
+		elapsed = ActionPhaseSeconds - e.Time
+	}
+
+	// The timer is only read every so often; never let the clock run backwards
+	if elapsed < c.last {
+		elapsed = c.last
+	}
+	c.last = elapsed
+
+	if e.Type == "DefuserPlantComplete" && !c.planted {
+		c.planted = true
+		c.plantedAt = elapsed
+	}
+	return elapsed
+}
+
+// RoundLength returns how long the round lasted. A round with no plant and
+// no eliminated team can only have ended when time ran out, whatever its win
+// condition says, since replays from Y9S4 on mostly leave it empty. A
+// defuser that went off ran its full length; anything else ended with the
+// last event seen.
+func (c *Clock) RoundLength(winCondition string, eliminated bool) float64 {
+	switch {
+	case !c.planted && !eliminated:
+		return ActionPhaseSeconds
+	case winCondition == "DefusedBomb" && c.planted:
+		return c.plantedAt + DefuserSeconds
+	}
+	return c.last
+}
+
+// Survival is how a player's round ended
+type Survival struct {
+	Time     float64 // seconds alive in the action phase
+	Survived bool
+	Traded   bool // killed, and a teammate killed their killer within the trade window
+}
+
+// death is a player going down, in seconds since the action phase started.
+// killer is empty for deaths without a kill and players leaving.
+type death struct {
+	victim string
+	killer string
+	time   float64
+}
+
+// departed returns the player an event takes out of the round and who killed
+// them, both empty for events that don't
+func departed(e Event) (gone, killer string) {
+	switch e.Type {
+	case "Kill":
+		return e.Target, e.Username
+	case "Death", "PlayerLeave":
+		return e.Username, ""
+	}
+	return "", ""
+}
+
+// roundDeaths returns each player's first death in feed order, and the clock
+// after the last event
+func roundDeaths(events []Event) ([]death, *Clock) {
+	var clock Clock
+	seen := make(map[string]bool)
+	var deaths []death
+
+	for _, e := range events {
+		now := clock.Tick(e)
+
+		gone, killer := departed(e)
+		if gone == "" || seen[gone] {
+			continue
+		}
+		seen[gone] = true
+		deaths = append(deaths, death{victim: gone, killer: killer, time: now})
+	}
+	return deaths, &clock
+}
+
+// eliminated reports whether every player of either team in teams died
+func eliminated(deaths []death, teams map[string]int) bool {
+	var players, dead [2]int
+	for _, team := range teams {
+		if team == 0 || team == 1 {
+			players[team]++
+		}
+	}
+	for _, d := range deaths {
+		if team, ok := teams[d.victim]; ok && (team == 0 || team == 1) {
+			dead[team]++
+		}
+	}
+	return players[0] > 0 && dead[0] == players[0] || players[1] > 0 && dead[1] == players[1]
+}
+
+// RoundSurvival works out how long each player in teams (username -> team
+// index) lived and whether their death was traded within tradeWindow
+// seconds. Players who leave the match count as dead from that moment.
+func RoundSurvival(events []Event, teams map[string]int, winCondition string, tradeWindow float64) map[string]*Survival {
+	deaths, clock := roundDeaths(events)
+
+	length := clock.RoundLength(winCondition, eliminated(deaths, teams))
+	result := make(map[string]*Survival, len(teams))
+	for username := range teams {
+		result[username] = &Survival{Time: length, Survived: true}
+	}
+	for _, d := range deaths {
+		if s := result[d.victim]; s != nil {
+			s.Time, s.Survived = d.time, false
+		}
+	}
+
+	for _, t := range trades(deaths, teams, tradeWindow) {
+		if s := result[t.Victim]; s != nil {
+			s.Traded = true
+		}
+	}
+
+	return result
+}
+
+// Trade is a kill that avenged a teammate
+type Trade struct {
+	Victim  string  // the player whose death was avenged
+	Killer  string  // who killed Victim, and was then killed
+	Avenger string  // Victim's teammate who killed Killer
+	Time    float64 // when Killer died, in seconds since the action phase started
+}
+
+// RoundTrades returns every trade in a round: a player killed by an enemy
+// who is then killed by one of the player's teammates within window seconds
+func RoundTrades(events []Event, teams map[string]int, window float64) []Trade {
+	deaths, _ := roundDeaths(events)
+	return trades(deaths, teams, window)
+}
+
+func trades(deaths []death, teams map[string]int, window float64) []Trade {
+	var result []Trade
+	for i, d := range deaths {
+		victimTeam, ok := teams[d.victim]
+		if !ok || d.killer == "" {
+			continue
+		}
+		// Team kills can't be traded
+		if killerTeam, ok := teams[d.killer]; !ok || killerTeam == victimTeam {
+			continue
+		}
+		for _, later := range deaths[i+1:] {
+			if later.time-d.time > window {
+				break
+			}
+			if later.victim != d.killer {
+				continue
+			}
+			if team, ok := teams[later.killer]; ok && team == victimTeam && later.killer != d.victim {
+				result = append(result, Trade{Victim: d.victim, Killer: d.killer, Avenger: later.killer, Time: later.time})
+			}
+			break
+		}
+	}
+	return result
+}
+
+// KillStreaks returns, for each player who got a kill, the size of each run
+// of kills they made no more than window seconds apart, in order
+func KillStreaks(events []Event, window float64) map[string][]int {
+	var clock Clock
+	last := make(map[string]float64)
+	streaks := make(map[string][]int)
+
+	for _, e := range events {
+		now := clock.Tick(e)
+		if e.Type != "Kill" || e.Username == "" {
+			continue
+		}
+		runs := streaks[e.Username]
+		if t, ok := last[e.Username]; ok && now-t <= window {
+			runs[len(runs)-1]++
+		} else {
+			runs = append(runs, 1)
+		}
+		streaks[e.Username] = runs
+		last[e.Username] = now
+	}
+	return streaks
+}
+
+// KOST reports whether a player got a Kill, played the Objective, Survived or
+// was Traded in a round
+func KOST(kills int, objective bool, s *Survival) bool {
+	return kills > 0 || objective || s.Survived || s.Traded
+}
@@ -0,0 +1,316 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+// kill is a kill with the countdown timer at timer
+func kill(timer float64, killer, victim string) Event {
+	return Event{Type: "Kill", Time: timer, Username: killer, Target: victim}
+}
+
+func event(eventType string, timer float64, username string) Event {
+	return Event{Type: eventType, Time: timer, Username: username}
+}
+
+func TestClockTick(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []Event
+		want   []float64
+	}{
+		{
+			name:   "countdown becomes time since the action phase started",
+			events: []Event{kill(170, "a1", "b1"), kill(100, "a1", "b2"), kill(0, "a1", "b3")},
+			want:   []float64{10, 80, 180},
+		},
+		{
+			name: "the defuser timer continues from the plant",
+			events: []Event{
+				kill(120, "a1", "b1"),
+				event("DefuserPlantComplete", 100, "a1"),
+				kill(40, "b2", "a1"), // 5 seconds after the plant on the defuser timer
+				event("DefuserDisableComplete", 0, "b2"),
+			},
+			want: []float64{60, 80, 85, 125},
+		},
+		{
+			name: "preparation phase and late events are placed at the last timed event",
+			events: []Event{
+				event("OperatorSwap", 28, "a1"),
+				kill(150, "a1", "b1"),
+				event("PlayerLeave", 7, "b2"),
+			},
+			want: []float64{0, 30, 30},
+		},
+		{
+			name:   "the clock never runs backwards",
+			events: []Event{kill(100, "a1", "b1"), kill(110, "a1", "b2")},
+			want:   []float64{80, 80},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var clock Clock
+			var got []float64
+			for _, e := range tt.events {
+				got = append(got, clock.Tick(e))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tick() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClockRoundLength(t *testing.T) {
+	plant := []Event{kill(150, "a1", "b1"), event("DefuserPlantComplete", 100, "a1")}
+	tests := []struct {
+		name         string
+		events       []Event
+		winCondition string
+		eliminated   bool
+		want         float64
+	}{
+		{"time ran out", []Event{kill(150, "a1", "b1")}, "Time", false, 180},
+		{"time ran out without a win condition", []Event{kill(150, "a1", "b1")}, "", false, 180},
+		{"without a plant or an elimination the clock ran out", []Event{kill(150, "a1", "b1")}, "KilledOpponents", false, 180},
+		{"the defuser went off", plant, "DefusedBomb", false, 125},
+		{"a planted round lost to time still ended at the last event", plant, "Time", false, 80},
+		{"eliminations end at the last kill", []Event{kill(150, "a1", "b1"), kill(120, "a1", "b2")}, "KilledOpponents", true, 60},
+		{"eliminations without a win condition end at the last kill", []Event{kill(150, "a1", "b1"), kill(120, "a1", "b2")}, "", true, 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var clock Clock
+			for _, e := range tt.events {
+				clock.Tick(e)
+			}
+			if got := clock.RoundLength(tt.winCondition, tt.eliminated); got != tt.want {
+				t.Errorf("RoundLength(%q, %v) = %v, want %v", tt.winCondition, tt.eliminated, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundSurvival(t *testing.T) {
+	teams := map[string]int{"a1": 0, "a2": 0, "b1": 1, "b2": 1}
+	tests := []struct {
+		name         string
+		events       []Event
+		winCondition string
+		want         map[string]*Survival
+	}{
+		{
+			name:         "survivors live until the round ends",
+			events:       []Event{kill(170, "a1", "b1"), kill(140, "a1", "b2")},
+			winCondition: "KilledOpponents",
+			want: map[string]*Survival{
+				"a1": {Time: 40, Survived: true},
+				"a2": {Time: 40, Survived: true},
+				"b1": {Time: 10},
+				"b2": {Time: 40},
+			},
+		},
+		{
+			name:         "a round lost to time lasts the full action phase",
+			events:       []Event{kill(170, "a1", "b1"), event("PlayerLeave", 100, "a2")},
+			winCondition: "Time",
+			want: map[string]*Survival{
+				"a1": {Time: 180, Survived: true},
+				"a2": {Time: 10}, // left, logged with the last timed event
+				"b1": {Time: 10},
+				"b2": {Time: 180, Survived: true},
+			},
+		},
+		{
+			name:         "a round without a win condition or an elimination lasts the full action phase",
+			events:       []Event{kill(170, "a1", "b1")},
+			winCondition: "",
+			want: map[string]*Survival{
+				"a1": {Time: 180, Survived: true},
+				"a2": {Time: 180, Survived: true},
+				"b1": {Time: 10},
+				"b2": {Time: 180, Survived: true},
+			},
+		},
+		{
+			name:         "only the first death counts",
+			events:       []Event{kill(170, "a1", "b1"), kill(160, "a2", "b1"), event("Death", 150, "b2")},
+			winCondition: "KilledOpponents",
+			want: map[string]*Survival{
+				"a1": {Time: 30, Survived: true},
+				"a2": {Time: 30, Survived: true},
+				"b1": {Time: 10},
+				"b2": {Time: 30},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RoundSurvival(tt.events, teams, tt.winCondition, TradeWindowSeconds)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RoundSurvival() = %v, want %v", describe(got), describe(tt.want))
+			}
+		})
+	}
+}
+
+// describe dereferences survival results for test failure messages
+func describe(survival map[string]*Survival) map[string]Survival {
+	out := make(map[string]Survival, len(survival))
+	for username, s := range survival {
+		out[username] = *s
+	}
+	return out
+}
+
+func TestKOST(t *testing.T) {
+	tests := []struct {
+		name      string
+		kills     int
+		objective bool
+		survival  Survival
+		want      bool
+	}{
+		{"kill", 1, false, Survival{}, true},
+		{"objective", 0, true, Survival{}, true},
+		{"survived", 0, false, Survival{Survived: true}, true},
+		{"traded", 0, false, Survival{Traded: true}, true},
+		{"died without anything", 0, false, Survival{Time: 30}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KOST(tt.kills, tt.objective, &tt.survival); got != tt.want {
+				t.Errorf("KOST() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrades(t *testing.T) {
+	teams := map[string]int{"a1": 0, "a2": 0, "a3": 0, "b1": 1, "b2": 1}
+	tests := []struct {
+		name   string
+		events []Event
+		want   []Trade
+	}{
+		{
+			name:   "a teammate kills the killer inside the window",
+			events: []Event{kill(170, "b1", "a1"), kill(168, "a2", "b1")},
+			want:   []Trade{{Victim: "a1", Killer: "b1", Avenger: "a2", Time: 12}},
+		},
+		{
+			name:   "the window edge is still a trade",
+			events: []Event{kill(170, "b1", "a1"), kill(167, "a2", "b1")},
+			want:   []Trade{{Victim: "a1", Killer: "b1", Avenger: "a2", Time: 13}},
+		},
+		{
+			name:   "too late to be a trade",
+			events: []Event{kill(170, "b1", "a1"), kill(166, "a2", "b1")},
+		},
+		{
+			name:   "the killer dying to their own team is not a trade",
+			events: []Event{kill(170, "b1", "a1"), kill(169, "b2", "b1")},
+		},
+		{
+			name:   "a team kill can't be traded",
+			events: []Event{kill(170, "a2", "a1"), kill(169, "a3", "a2")},
+		},
+		{
+			name:   "a suicide is not avenged",
+			events: []Event{event("Death", 170, "a1"), kill(169, "a2", "b1")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RoundTrades(tt.events, teams, TradeWindowSeconds)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RoundTrades() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundSurvivalTraded(t *testing.T) {
+	teams := map[string]int{"a1": 0, "a2": 0, "b1": 1, "b2": 1}
+	events := []Event{
+		kill(170, "b1", "a1"), kill(169, "a2", "b1"), // a1 is traded by a2
+		kill(150, "b2", "a2"), kill(149, "b2", "b2"), // b2 killing themselves doesn't trade a2
+	}
+
+	got := RoundSurvival(events, teams, "KilledOpponents", TradeWindowSeconds)
+	if !got["a1"].Traded {
+		t.Error("a1 was not traded by their teammate")
+	}
+	for _, username := range []string{"a2", "b1", "b2"} {
+		if got[username].Traded {
+			t.Errorf("%s was traded, want not traded", username)
+		}
+	}
+}
+
+func TestKillStreaks(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []Event
+		window float64
+		want   map[string][]int
+	}{
+		{
+			name:   "kills at the window edge are one streak",
+			events: []Event{kill(170, "a1", "b1"), kill(160, "a1", "b2")},
+			window: MultiKillWindowSeconds,
+			want:   map[string][]int{"a1": {2}},
+		},
+		{
+			name:   "kills just outside the window start a new streak",
+			events: []Event{kill(170, "a1", "b1"), kill(159, "a1", "b2"), kill(155, "a1", "b3")},
+			window: MultiKillWindowSeconds,
+			want:   map[string][]int{"a1": {1, 2}},
+		},
+		{
+			name:   "the window is measured from the previous kill, not the first",
+			events: []Event{kill(170, "a1", "b1"), kill(162, "a1", "b2"), kill(154, "a1", "b3")},
+			window: MultiKillWindowSeconds,
+			want:   map[string][]int{"a1": {3}},
+		},
+		{
+			name:   "a shorter window splits the same kills",
+			events: []Event{kill(170, "a1", "b1"), kill(162, "a1", "b2"), kill(154, "a1", "b3")},
+			window: 5,
+			want:   map[string][]int{"a1": {1, 1, 1}},
+		},
+		{
+			name: "the streak spans the plant on the defuser timer",
+			events: []Event{
+				kill(110, "a1", "b1"),                    // 70s
+				event("DefuserPlantComplete", 105, "a2"), // 75s
+				kill(42, "a1", "b2"),                     // 78s
+			},
+			window: MultiKillWindowSeconds,
+			want:   map[string][]int{"a1": {2}},
+		},
+		{
+			name:   "streaks are kept per player",
+			events: []Event{kill(170, "a1", "b1"), kill(169, "b2", "a2"), kill(168, "a1", "b3")},
+			window: MultiKillWindowSeconds,
+			want:   map[string][]int{"a1": {2}, "b2": {1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := KillStreaks(tt.events, tt.window)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("KillStreaks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package analysis
+
+// Moment is where an event falls in its round
+type Moment struct {
+	Time  float64 // seconds since the action phase started
+	Alive [2]int  // players still alive on team index 0 and 1 after the event
+}
+
+// RoundTimeline places every event of a round on the round clock and counts
+// the players of teams (username -> team index) left alive after each, one
+// Moment per event. Events must be in feed order. It also returns the round
+// length, as RoundLength does.
+func RoundTimeline(events []Event, teams map[string]int, winCondition string) ([]Moment, float64) {
+	var players [2]int
+	for _, team := range teams {
+		if team == 0 || team == 1 {
+			players[team]++
+		}
+	}
+	alive := players
+
+	var clock Clock
+	seen := make(map[string]bool)
+	moments := make([]Moment, len(events))
+	for i, e := range events {
+		now := clock.Tick(e)
+
+		gone, _ := departed(e)
+		if team, ok := teams[gone]; ok && !seen[gone] && (team == 0 || team == 1) {
+			seen[gone] = true
+			alive[team]--
+		}
+		moments[i] = Moment{Time: now, Alive: alive}
+	}
+	eliminated := players[0] > 0 && alive[0] == 0 || players[1] > 0 && alive[1] == 0
+	return moments, clock.RoundLength(winCondition, eliminated)
+}
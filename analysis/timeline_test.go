@@ -0,0 +1,36 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRoundTimeline(t *testing.T) {
+	teams := map[string]int{"a1": 0, "a2": 0, "b1": 1, "b2": 1, "b3": 1}
+	events := []Event{
+		event("OperatorSwap", 30, "a1"),
+		kill(170, "a1", "b1"),
+		event("DefuserPlantComplete", 100, "b2"),
+		kill(40, "a2", "b2"),
+		event("Death", 38, "a1"),
+		event("PlayerLeave", 5, "b2"), // already dead, so not counted again
+		event("PlayerLeave", 5, "b3"),
+	}
+
+	got, length := RoundTimeline(events, teams, "DefusedBomb")
+	want := []Moment{
+		{Time: 0, Alive: [2]int{2, 3}},
+		{Time: 10, Alive: [2]int{2, 2}},
+		{Time: 80, Alive: [2]int{2, 2}},
+		{Time: 85, Alive: [2]int{2, 1}},
+		{Time: 87, Alive: [2]int{1, 1}},
+		{Time: 87, Alive: [2]int{1, 1}},
+		{Time: 87, Alive: [2]int{1, 0}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RoundTimeline() = %+v, want %+v", got, want)
+	}
+	if length != 125 {
+		t.Errorf("round length = %v, want 125", length)
+	}
+}
@@ -0,0 +1,185 @@
+// Package cli implements the headless siegescope command (cmd/siegescope), so
+// imports, stats and exports can be scripted without opening the window.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog"
+
+	"r6-replay-recorder/database"
+	"r6-replay-recorder/parser"
+)
+
+// Exit codes returned by Run
+const (
+	ExitOK      = 0 // command succeeded
+	ExitError   = 1 // command failed
+	ExitUsage   = 2 // bad command line
+	ExitPartial = 3 // import or reanalyze finished but some folders or matches failed
+)
+
+const usage = `Usage: siegescope [--db path] [-v] <command> [flags] [args]
+
+Commands:
+  import [--workers N] [--force] [--json] <path>
+                                         Import a match folder, a .rec file or a whole replay tree.
+                                         Folders unchanged since their last import are skipped
+                                         unless --force is given
+  reanalyze [--all] [--json]             Recompute player stats of matches analyzed by an older
+                                         version from their replay files, or of every match with --all
+  list [--map M] [--type T] [--result win|loss] [--limit N] [--json]
+                                         List imported matches, newest first
+  stats [--side attack|defense] [--by B] [--json] maps|clutch|defuser|rating|wins|openings
+                                         Print aggregate statistics, optionally for one side only.
+                                         With a side, map wins and losses count rounds. wins breaks
+                                         rounds down by win condition, with a weekly trend. openings
+                                         groups opening duels by player, operator, side, map or site
+  export [--out file]                    Write the JSON archive (stdout by default)
+
+Global flags:
+  --db path   Use this replays.db instead of the one in the app data folder
+  -v          Log import progress to stderr
+
+Exit codes: 0 ok, 1 error, 2 usage, 3 import or reanalyze finished with failures
+`
+
+type command func(env *env, args []string) int
+
+var commands = map[string]command{
+	"import":    runImport,
+	"reanalyze": runReanalyze,
+	"list":      runList,
+	"stats":     runStats,
+	"export":    runExport,
+}
+
+// env carries the open database and output streams into each command
+type env struct {
+	ctx    context.Context // cancelled on Ctrl-C
+	db     *database.Database
+	parser *parser.Parser
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Run executes a command line and returns the process exit code
+func Run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("siegescope", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usage) }
+	dbPath := fs.String("db", "", "path to replays.db")
+	verbose := fs.Bool("v", false, "log progress to stderr")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	if fs.NArg() == 0 || fs.Arg(0) == "help" {
+		fmt.Fprint(stderr, usage)
+		if fs.NArg() == 0 {
+			return ExitUsage
+		}
+		return ExitOK
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", fs.Arg(0), usage)
+		return ExitUsage
+	}
+
+	// The parser and r6-dissect log every step; keep output clean for scripts
+	if *verbose {
+		log.SetOutput(stderr)
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	} else {
+		log.SetOutput(io.Discard)
+		zerolog.SetGlobalLevel(zerolog.Disabled)
+	}
+
+	var db *database.Database
+	var err error
+	if *dbPath != "" {
+		db, err = database.Open(*dbPath)
+	} else {
+		db, err = database.New()
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return ExitError
+	}
+	defer db.Close()
+
+	// Ctrl-C stops an import cleanly instead of killing it mid-write
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return cmd(&env{
+		ctx:    ctx,
+		db:     db,
+		parser: parser.New(db),
+		stdout: stdout,
+		stderr: stderr,
+	}, fs.Args()[1:])
+}
+
+// newFlagSet builds a subcommand flag set that reports errors to stderr
+func (e *env) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() { fmt.Fprint(e.stderr, usage) }
+	return fs
+}
+
+func (e *env) fail(err error) int {
+	fmt.Fprintf(e.stderr, "error: %v\n", err)
+	return ExitError
+}
+
+func (e *env) writeJSON(v interface{}) int {
+	enc := json.NewEncoder(e.stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return e.fail(err)
+	}
+	return ExitOK
+}
+
+func (e *env) newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+}
+
+// parseArgs parses flags that may appear before or after the positional
+// arguments and checks how many positional arguments were given
+func parseArgs(fs *flag.FlagSet, args []string, positional int) ([]string, bool) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, false
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if len(rest) != positional {
+		fs.Usage()
+		return nil, false
+	}
+	return rest, true
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	tempDB := func(t *testing.T) string { return filepath.Join(t.TempDir(), "replays.db") }
+
+	// Two match folders whose rounds can't be decoded
+	replays := t.TempDir()
+	for _, folder := range []string{"Match-2024-01-01_12-00-00-000", "Match-2024-01-02_12-00-00-000"} {
+		dir := filepath.Join(replays, folder)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "R01_00000000.rec"), []byte("not a replay"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		args func(t *testing.T) []string
+		want int
+	}{
+		{"no command", func(t *testing.T) []string { return nil }, ExitUsage},
+		{"help", func(t *testing.T) []string { return []string{"help"} }, ExitOK},
+		{"unknown command", func(t *testing.T) []string { return []string{"--db", tempDB(t), "frobnicate"} }, ExitUsage},
+		{"unknown global flag", func(t *testing.T) []string { return []string{"--nope", "list"} }, ExitUsage},
+		{"missing import path", func(t *testing.T) []string { return []string{"--db", tempDB(t), "import"} }, ExitUsage},
+		{"unknown stats kind", func(t *testing.T) []string { return []string{"--db", tempDB(t), "stats", "nope"} }, ExitUsage},
+		{"bad --db", func(t *testing.T) []string {
+			return []string{"--db", filepath.Join(t.TempDir(), "missing", "replays.db"), "list"}
+		}, ExitError},
+		{"list empty library", func(t *testing.T) []string { return []string{"--db", tempDB(t), "list", "--json"} }, ExitOK},
+		{"import missing path", func(t *testing.T) []string {
+			return []string{"--db", tempDB(t), "import", filepath.Join(t.TempDir(), "missing")}
+		}, ExitError},
+		{"partial import", func(t *testing.T) []string { return []string{"--db", tempDB(t), "import", replays} }, ExitPartial},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := Run(tt.args(t), &stdout, &stderr); got != tt.want {
+				t.Errorf("Run = %d, want %d\nstdout: %s\nstderr: %s", got, tt.want, stdout.String(), stderr.String())
+			}
+		})
+	}
+}
+
+func TestListJSONIsEmptyArray(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"--db", filepath.Join(t.TempDir(), "replays.db"), "list", "--json"}, &stdout, &stderr); code != ExitOK {
+		t.Fatalf("Run = %d: %s", code, stderr.String())
+	}
+	if got := stdout.String(); got != "[]\n" {
+		t.Errorf("list --json on an empty library = %q, want %q", got, "[]\n")
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		positional int
+		wantRest   []string
+		wantJSON   bool
+		wantLimit  int
+		wantOK     bool
+	}{
+		{"flags first", []string{"--json", "--limit", "5", "maps"}, 1, []string{"maps"}, true, 5, true},
+		{"flags after positional", []string{"maps", "--json", "--limit", "5"}, 1, []string{"maps"}, true, 5, true},
+		{"flags between positionals", []string{"a", "--json", "b"}, 2, []string{"a", "b"}, true, 0, true},
+		{"no positionals", []string{"--limit=3"}, 0, nil, false, 3, true},
+		{"missing positional", []string{"--json"}, 1, nil, false, 0, false},
+		{"extra positional", []string{"a", "b"}, 1, nil, false, 0, false},
+		{"unknown flag after positional", []string{"a", "--nope"}, 1, nil, false, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			fs.Usage = func() {}
+			asJSON := fs.Bool("json", false, "")
+			limit := fs.Int("limit", 0, "")
+
+			rest, ok := parseArgs(fs, tt.args, tt.positional)
+			if ok != tt.wantOK {
+				t.Fatalf("parseArgs ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if !reflect.DeepEqual(rest, tt.wantRest) {
+				t.Errorf("positional = %q, want %q", rest, tt.wantRest)
+			}
+			if *asJSON != tt.wantJSON || *limit != tt.wantLimit {
+				t.Errorf("--json = %v, --limit = %d; want %v, %d", *asJSON, *limit, tt.wantJSON, tt.wantLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,422 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"r6-replay-recorder/database"
+	"r6-replay-recorder/models"
+	"r6-replay-recorder/parser"
+)
+
+// importResult is one line of `import` output
+type importResult struct {
+	Folder  string `json:"folder"`
+	Status  string `json:"status"` // imported, skipped or failed
+	MatchID string `json:"matchId,omitempty"`
+	Map     string `json:"map,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type importReport struct {
+	Imported    int            `json:"imported"`
+	Skipped     int            `json:"skipped"`
+	Failed      int            `json:"failed"`
+	Unchanged   int            `json:"unchanged"` // skipped by the import ledger without decoding
+	Interrupted bool           `json:"interrupted,omitempty"`
+	Results     []importResult `json:"results"`
+}
+
+func runImport(e *env, args []string) int {
+	fs := e.newFlagSet("import")
+	asJSON := fs.Bool("json", false, "print JSON")
+	workers := fs.Int("workers", 0, "decode this many folders at once (default: one per CPU)")
+	force := fs.Bool("force", false, "re-read folders the import ledger has already seen, including failed ones")
+	pos, ok := parseArgs(fs, args, 1)
+	if !ok {
+		return ExitUsage
+	}
+	root := pos[0]
+
+	if _, err := os.Stat(root); err != nil {
+		return e.fail(err)
+	}
+
+	// A replay tree yields its match folders; a single match folder or .rec
+	// file yields nothing and is imported directly
+	var folders []string
+	var unchanged int
+	var err error
+	if *force {
+		folders, err = e.parser.FindReplayFolders(e.ctx, root)
+	} else {
+		folders, unchanged, err = e.parser.FindPendingReplayFolders(e.ctx, root)
+	}
+	if err != nil {
+		return e.fail(err)
+	}
+	if len(folders) == 0 && unchanged == 0 {
+		folders = []string{root}
+	}
+
+	report := importReport{Unchanged: unchanged, Results: []importResult{}}
+	status, err := e.parser.ImportMany(e.ctx, folders, *workers, func(p parser.ImportProgress) {
+		result := importResult{Folder: p.Last.Folder}
+		switch {
+		case p.Last.Err != nil:
+			result.Status = "failed"
+			result.Error = p.Last.Err.Error()
+		case p.Last.Match != nil:
+			result.Status = "imported"
+			result.MatchID = p.Last.Match.MatchID
+			result.Map = p.Last.Match.Map
+		default:
+			result.Status = "skipped"
+		}
+		report.Results = append(report.Results, result)
+	})
+	report.Imported, report.Skipped, report.Failed = status.Imported, status.Skipped, status.Failed
+	report.Interrupted = err != nil
+
+	code := ExitOK
+	switch {
+	case err != nil:
+		fmt.Fprintf(e.stderr, "import interrupted after %d of %d folders: %v\n", status.Done, len(folders), err)
+		code = ExitError
+	case report.Failed > 0:
+		code = ExitPartial
+	}
+
+	if *asJSON {
+		if rc := e.writeJSON(report); rc != ExitOK {
+			return rc
+		}
+		return code
+	}
+
+	tw := e.newTable()
+	fmt.Fprintln(tw, "STATUS\tMAP\tFOLDER\tDETAIL")
+	for _, r := range report.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Status, r.Map, filepath.Base(r.Folder), r.Error)
+	}
+	tw.Flush()
+	fmt.Fprintf(e.stdout, "\n%d imported, %d skipped, %d failed, %d unchanged\n", report.Imported, report.Skipped, report.Failed, report.Unchanged)
+
+	return code
+}
+
+// reanalyzeResult is one line of `reanalyze` output
+type reanalyzeResult struct {
+	MatchID string `json:"matchId"`
+	Map     string `json:"map,omitempty"`
+	Status  string `json:"status"` // updated or failed
+	Error   string `json:"error,omitempty"`
+}
+
+type reanalyzeReport struct {
+	Updated     int               `json:"updated"`
+	Failed      int               `json:"failed"`
+	Interrupted bool              `json:"interrupted,omitempty"`
+	Results     []reanalyzeResult `json:"results"`
+}
+
+func runReanalyze(e *env, args []string) int {
+	fs := e.newFlagSet("reanalyze")
+	asJSON := fs.Bool("json", false, "print JSON")
+	all := fs.Bool("all", false, "re-analyze every match, not just those from an older analysis version")
+	if _, ok := parseArgs(fs, args, 0); !ok {
+		return ExitUsage
+	}
+
+	report := reanalyzeReport{Results: []reanalyzeResult{}}
+	status, err := e.parser.ReanalyzeLibrary(e.ctx, *all, func(p parser.ReanalyzeProgress) {
+		result := reanalyzeResult{MatchID: p.Last.Match.MatchID, Map: p.Last.Match.Map, Status: "updated"}
+		if p.Last.Err != nil {
+			result.Status = "failed"
+			result.Error = p.Last.Err.Error()
+		}
+		report.Results = append(report.Results, result)
+	})
+	report.Updated, report.Failed = status.Updated, status.Failed
+	report.Interrupted = err != nil
+
+	code := ExitOK
+	switch {
+	case err != nil:
+		fmt.Fprintf(e.stderr, "reanalyze interrupted after %d of %d matches: %v\n", status.Done, status.Total, err)
+		code = ExitError
+	case report.Failed > 0:
+		code = ExitPartial
+	}
+
+	if *asJSON {
+		if rc := e.writeJSON(report); rc != ExitOK {
+			return rc
+		}
+		return code
+	}
+
+	tw := e.newTable()
+	fmt.Fprintln(tw, "STATUS\tMAP\tMATCH\tDETAIL")
+	for _, r := range report.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Status, r.Map, r.MatchID, r.Error)
+	}
+	tw.Flush()
+	fmt.Fprintf(e.stdout, "\n%d updated, %d failed\n", report.Updated, report.Failed)
+
+	return code
+}
+
+func runList(e *env, args []string) int {
+	fs := e.newFlagSet("list")
+	mapName := fs.String("map", "", "only this map")
+	matchType := fs.String("type", "", "only this match type (Ranked, QuickMatch, ...)")
+	result := fs.String("result", "", "win or loss")
+	limit := fs.Int("limit", 0, "show at most N matches")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if _, ok := parseArgs(fs, args, 0); !ok {
+		return ExitUsage
+	}
+
+	var won *bool
+	switch strings.ToLower(*result) {
+	case "":
+	case "win", "wins":
+		w := true
+		won = &w
+	case "loss", "losses":
+		w := false
+		won = &w
+	default:
+		fmt.Fprintf(e.stderr, "invalid --result %q (want win or loss)\n", *result)
+		return ExitUsage
+	}
+
+	matches, err := e.db.GetMatchesByFilter(*matchType, *mapName, won)
+	if err != nil {
+		return e.fail(err)
+	}
+	if *limit > 0 && len(matches) > *limit {
+		matches = matches[:*limit]
+	}
+
+	if *asJSON {
+		return e.writeJSON(database.EmptyIfNil(matches))
+	}
+
+	tw := e.newTable()
+	fmt.Fprintln(tw, "ID\tDATE\tMAP\tTYPE\tSCORE\tRESULT\tMATCH ID")
+	for _, m := range matches {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d-%d\t%s\t%s\n",
+			m.ID, m.Timestamp.Format("2006-01-02 15:04"), m.Map, m.MatchType,
+			m.TeamScore, m.OpponentScore, resultLabel(m.Won), m.MatchID)
+	}
+	tw.Flush()
+	return ExitOK
+}
+
+// winsReport is the JSON output of `stats wins`
+type winsReport struct {
+	Conditions []models.WinConditionStats `json:"conditions"`
+	Trend      []models.WinConditionTrend `json:"trend"`
+}
+
+// openingDuelGroup names the group of an opening duel row for `stats openings`
+func openingDuelGroup(s models.OpeningDuelStats) string {
+	switch {
+	case s.Username != "":
+		return s.Username
+	case s.Operator != "":
+		return s.Operator
+	case s.Side != "":
+		return s.Side
+	case s.Site != "":
+		return s.Map + " - " + s.Site
+	}
+	return s.Map
+}
+
+func runStats(e *env, args []string) int {
+	fs := e.newFlagSet("stats")
+	sideFlag := fs.String("side", "", "only count rounds on this side (attack or defense)")
+	by := fs.String("by", database.OpeningDuelsByPlayer, "group opening duels by player, operator, side, map or site")
+	asJSON := fs.Bool("json", false, "print JSON")
+	pos, ok := parseArgs(fs, args, 1)
+	if !ok {
+		return ExitUsage
+	}
+
+	var side string
+	switch strings.ToLower(*sideFlag) {
+	case "":
+	case "attack", "atk":
+		side = "Attack"
+	case "defense", "defence", "def":
+		side = "Defense"
+	default:
+		fmt.Fprintf(e.stderr, "invalid --side %q (want attack or defense)\n", *sideFlag)
+		return ExitUsage
+	}
+
+	switch pos[0] {
+	case "maps":
+		stats, err := e.db.GetMapStats(side)
+		if err != nil {
+			return e.fail(err)
+		}
+		if *asJSON {
+			return e.writeJSON(database.EmptyIfNil(stats))
+		}
+		tw := e.newTable()
+		fmt.Fprintln(tw, "MAP\tPLAYED\tWINS\tLOSSES\tWIN %\tATK %\tDEF %\tAVG ROUNDS")
+		for _, s := range stats {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.1f\t%.1f\t%.1f\t%.1f\n", s.MapName, s.Played, s.Wins, s.Losses, s.WinRate,
+				s.AttackWinRate, s.DefenseWinRate, s.AvgRounds)
+		}
+		tw.Flush()
+
+	case "clutch":
+		stats, err := e.db.GetClutchStats(side)
+		if err != nil {
+			return e.fail(err)
+		}
+		if *asJSON {
+			return e.writeJSON(database.EmptyIfNil(stats))
+		}
+		tw := e.newTable()
+		fmt.Fprintln(tw, "PLAYER\t1v1\t1v2\t1v3\t1v4\t1v5\tWIN %")
+		for _, s := range stats {
+			fmt.Fprintf(tw, "%s\t%d/%d\t%d/%d\t%d/%d\t%d/%d\t%d/%d\t%.1f\n", s.Username,
+				s.Clutch1v1Won, s.Clutch1v1, s.Clutch1v2Won, s.Clutch1v2, s.Clutch1v3Won, s.Clutch1v3,
+				s.Clutch1v4Won, s.Clutch1v4, s.Clutch1v5Won, s.Clutch1v5, s.ClutchRate)
+		}
+		tw.Flush()
+
+	case "defuser":
+		stats, err := e.db.GetDefuserStats(side)
+		if err != nil {
+			return e.fail(err)
+		}
+		if *asJSON {
+			return e.writeJSON(database.EmptyIfNil(stats))
+		}
+		tw := e.newTable()
+		fmt.Fprintln(tw, "PLAYER\tPLANTS\tDEFUSES\tDENIALS\tPLANT %")
+		for _, s := range stats {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.1f\n", s.Username, s.Plants, s.Defuses, s.PlantDenials, s.PlantSuccessRate)
+		}
+		tw.Flush()
+
+	case "rating":
+		stats, err := e.db.GetRatingStats(side)
+		if err != nil {
+			return e.fail(err)
+		}
+		if *asJSON {
+			return e.writeJSON(database.EmptyIfNil(stats))
+		}
+		tw := e.newTable()
+		fmt.Fprintln(tw, "PLAYER\tROUNDS\tRATING\tBEST MATCH")
+		for _, s := range stats {
+			fmt.Fprintf(tw, "%s\t%d\t%.2f\t%.2f\n", s.Username, s.Rounds, s.Rating, s.Best)
+		}
+		tw.Flush()
+
+	case "wins":
+		stats, err := e.db.GetWinConditionStats("", side)
+		if err != nil {
+			return e.fail(err)
+		}
+		trend, err := e.db.GetWinConditionTrend("", side)
+		if err != nil {
+			return e.fail(err)
+		}
+		if *asJSON {
+			return e.writeJSON(winsReport{Conditions: database.EmptyIfNil(stats), Trend: database.EmptyIfNil(trend)})
+		}
+		tw := e.newTable()
+		fmt.Fprintln(tw, "SIDE\tCONDITION\tWON\tLOST\tLOST %")
+		for _, s := range stats {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%.1f\n", s.Side, s.WinCondition, s.Won, s.Lost, s.LostShare)
+		}
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "WEEK\tROUNDS\tLOST\tTO TIME\tTO PLANT")
+		for _, t := range trend {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d (%.0f%%)\t%d (%.0f%%)\n", t.Week.Format("2006-01-02"),
+				t.Rounds, t.Lost, t.LostToTime, t.TimeShare, t.LostToPlant, t.PlantShare)
+		}
+		tw.Flush()
+
+	case "openings":
+		group := strings.ToLower(*by)
+		switch group {
+		case database.OpeningDuelsByPlayer, database.OpeningDuelsByOperator, database.OpeningDuelsBySide,
+			database.OpeningDuelsByMap, database.OpeningDuelsBySite:
+		default:
+			fmt.Fprintf(e.stderr, "invalid --by %q (want player, operator, side, map or site)\n", *by)
+			return ExitUsage
+		}
+		stats, err := e.db.GetOpeningDuelStats(group, models.StatsFilter{Role: side})
+		if err != nil {
+			return e.fail(err)
+		}
+		if *asJSON {
+			return e.writeJSON(database.EmptyIfNil(stats))
+		}
+		tw := e.newTable()
+		fmt.Fprintln(tw, "GROUP\tDUELS\tWON\tLOST\t+/-\tWON %\tROUND WIN % AFTER WIN\tAFTER LOSS")
+		for _, s := range stats {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%+d\t%.1f\t%.1f\t%.1f\n", openingDuelGroup(s),
+				s.Duels, s.Won, s.Lost, s.Differential, s.WinRate, s.RoundWinRateAfterWin, s.RoundWinRateAfterLoss)
+		}
+		tw.Flush()
+
+	default:
+		fmt.Fprintf(e.stderr, "unknown stats report %q (want maps, clutch, defuser, rating, wins or openings)\n", pos[0])
+		return ExitUsage
+	}
+
+	return ExitOK
+}
+
+func runExport(e *env, args []string) int {
+	fs := e.newFlagSet("export")
+	out := fs.String("out", "-", "output file, - for stdout")
+	if _, ok := parseArgs(fs, args, 0); !ok {
+		return ExitUsage
+	}
+
+	var w io.Writer = e.stdout
+	var f *os.File
+	if *out != "-" {
+		var err error
+		f, err = os.Create(*out)
+		if err != nil {
+			return e.fail(err)
+		}
+		w = f
+	}
+
+	exported, err := e.db.ExportJSON(w)
+	if f != nil {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return e.fail(fmt.Errorf("export failed after %d matches: %w", exported, err))
+	}
+
+	fmt.Fprintf(e.stderr, "exported %d matches\n", exported)
+	return ExitOK
+}
+
+func resultLabel(won bool) string {
+	if won {
+		return "WIN"
+	}
+	return "LOSS"
+}
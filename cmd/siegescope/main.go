@@ -0,0 +1,14 @@
+// Command siegescope is the headless SiegeScope command line. It shares the
+// GUI's replays.db but doesn't link the window toolkit, so it builds and runs
+// on servers without a display.
+package main
+
+import (
+	"os"
+
+	"r6-replay-recorder/cli"
+)
+
+func main() {
+	os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"time"
 
 	"r6-replay-recorder/models"
@@ -14,7 +15,24 @@ import (
 )
 
 type Database struct {
-	db *sql.DB
+	db   *sql.DB
+	path string
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx so inserts can run in either
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx so reads can run in either
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
 // GetAppDataPath returns the appropriate application data directory for the OS
@@ -54,23 +72,29 @@ func GetAppDataPath() (string, error) {
 	return appPath, nil
 }
 
-// New creates and initializes the database
+// New creates and initializes the database in the application data directory
 func New() (*Database, error) {
 	appPath, err := GetAppDataPath()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get app data path: %w", err)
 	}
 
-	dbPath := filepath.Join(appPath, "replays.db")
+	return Open(filepath.Join(appPath, "replays.db"))
+}
 
-	db, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=on")
+// Open opens and initializes the database at dbPath. The library uses
+// write-ahead logging so long reads such as an export don't block imports,
+// and writers wait out each other's transactions instead of failing.
+func Open(dbPath string) (*Database, error) {
+	db, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=30000")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	database := &Database{db: db}
+	database := &Database{db: db, path: dbPath}
 
 	if err := database.initialize(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
@@ -78,161 +102,7 @@ func New() (*Database, error) {
 }
 
 func (d *Database) initialize() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS matches (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		match_id TEXT UNIQUE NOT NULL,
-		game_version TEXT,
-		code_version INTEGER,
-		timestamp DATETIME,
-		match_type TEXT,
-		game_mode TEXT,
-		map TEXT,
-		recording_player TEXT,
-		profile_id TEXT,
-		team_score INTEGER,
-		opponent_score INTEGER,
-		won BOOLEAN,
-		rounds_played INTEGER,
-		imported_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		file_path TEXT
-	);
-
-	CREATE TABLE IF NOT EXISTS rounds (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		match_id INTEGER NOT NULL,
-		round_number INTEGER,
-		site TEXT,
-		team_role TEXT,
-		won BOOLEAN,
-		win_condition TEXT,
-		team_score INTEGER,
-		opponent_score INTEGER,
-		FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE
-	);
-
-	CREATE TABLE IF NOT EXISTS players (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		round_id INTEGER NOT NULL,
-		match_id INTEGER NOT NULL,
-		profile_id TEXT,
-		username TEXT,
-		team_index INTEGER,
-		operator TEXT,
-		spawn TEXT,
-		FOREIGN KEY (round_id) REFERENCES rounds(id) ON DELETE CASCADE,
-		FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE
-	);
-
-	CREATE TABLE IF NOT EXISTS match_events (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		round_id INTEGER NOT NULL,
-		match_id INTEGER NOT NULL,
-		event_type TEXT,
-		time TEXT,
-		time_in_seconds INTEGER,
-		username TEXT,
-		target TEXT,
-		headshot BOOLEAN,
-		message TEXT,
-		FOREIGN KEY (round_id) REFERENCES rounds(id) ON DELETE CASCADE,
-		FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE
-	);
-
-	CREATE TABLE IF NOT EXISTS player_round_stats (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		round_id INTEGER NOT NULL,
-		match_id INTEGER NOT NULL,
-		username TEXT,
-		team_index INTEGER,
-		operator TEXT,
-		kills INTEGER DEFAULT 0,
-		died BOOLEAN DEFAULT 0,
-		assists INTEGER DEFAULT 0,
-		headshots INTEGER DEFAULT 0,
-		headshot_percentage REAL DEFAULT 0,
-		entry_kill BOOLEAN DEFAULT 0,
-		entry_death BOOLEAN DEFAULT 0,
-		defuser_plants INTEGER DEFAULT 0,
-		defuser_defuses INTEGER DEFAULT 0,
-		defuser_pickups INTEGER DEFAULT 0,
-		plant_denials INTEGER DEFAULT 0,
-		clutch_attempts INTEGER DEFAULT 0,
-		clutch_wins INTEGER DEFAULT 0,
-		clutch_1v1 BOOLEAN DEFAULT 0,
-		clutch_1v2 BOOLEAN DEFAULT 0,
-		clutch_1v3 BOOLEAN DEFAULT 0,
-		clutch_1v4 BOOLEAN DEFAULT 0,
-		clutch_1v5 BOOLEAN DEFAULT 0,
-		double_kills INTEGER DEFAULT 0,
-		triple_kills INTEGER DEFAULT 0,
-		quad_kills INTEGER DEFAULT 0,
-		ace BOOLEAN DEFAULT 0,
-		trade_kills INTEGER DEFAULT 0,
-		trade_deaths INTEGER DEFAULT 0,
-		survival_time REAL DEFAULT 0,
-		survived BOOLEAN DEFAULT 0,
-		FOREIGN KEY (round_id) REFERENCES rounds(id) ON DELETE CASCADE,
-		FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE
-	);
-
-	CREATE TABLE IF NOT EXISTS settings (
-		id INTEGER PRIMARY KEY CHECK (id = 1),
-		replay_folder TEXT,
-		auto_import BOOLEAN DEFAULT 0,
-		theme TEXT DEFAULT 'dark',
-		start_minimized BOOLEAN DEFAULT 0,
-		start_with_system BOOLEAN DEFAULT 0,
-		api_key TEXT
-	);
-
-	-- Insert default settings if not exists
-	INSERT OR IGNORE INTO settings (id, theme) VALUES (1, 'dark');
-
-	-- Create indexes for performance
-	CREATE INDEX IF NOT EXISTS idx_matches_timestamp ON matches(timestamp);
-	CREATE INDEX IF NOT EXISTS idx_matches_map ON matches(map);
-	CREATE INDEX IF NOT EXISTS idx_matches_match_type ON matches(match_type);
-	CREATE INDEX IF NOT EXISTS idx_rounds_match_id ON rounds(match_id);
-	CREATE INDEX IF NOT EXISTS idx_players_match_id ON players(match_id);
-	CREATE INDEX IF NOT EXISTS idx_players_username ON players(username);
-	CREATE INDEX IF NOT EXISTS idx_match_events_round_id ON match_events(round_id);
-	CREATE INDEX IF NOT EXISTS idx_player_round_stats_round_id ON player_round_stats(round_id);
-	CREATE INDEX IF NOT EXISTS idx_player_round_stats_match_id ON player_round_stats(match_id);
-	`
-
-	if _, err := d.db.Exec(schema); err != nil {
-		return err
-	}
-
-	// Migration: Add new columns if they don't exist (for existing databases)
-	migrations := []string{
-		"ALTER TABLE player_round_stats ADD COLUMN defuser_plants INTEGER DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN defuser_defuses INTEGER DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN defuser_pickups INTEGER DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN plant_denials INTEGER DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN clutch_attempts INTEGER DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN clutch_wins INTEGER DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN clutch_1v1 BOOLEAN DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN clutch_1v2 BOOLEAN DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN clutch_1v3 BOOLEAN DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN clutch_1v4 BOOLEAN DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN clutch_1v5 BOOLEAN DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN double_kills INTEGER DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN triple_kills INTEGER DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN quad_kills INTEGER DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN ace BOOLEAN DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN trade_kills INTEGER DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN trade_deaths INTEGER DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN survival_time REAL DEFAULT 0",
-		"ALTER TABLE player_round_stats ADD COLUMN survived BOOLEAN DEFAULT 0",
-	}
-
-	for _, migration := range migrations {
-		_, _ = d.db.Exec(migration) // Ignore errors (column may already exist)
-	}
-
-	return nil
+	return d.migrate()
 }
 
 // Close closes the database connection
@@ -247,17 +117,31 @@ func (d *Database) MatchExists(matchID string) (bool, error) {
 	return count > 0, err
 }
 
+// GetStoredRoundCount returns how many rounds are stored for a match, 0 if
+// it isn't in the library
+func (d *Database) GetStoredRoundCount(matchID string) (int, error) {
+	var count int
+	err := d.db.QueryRow(`
+		SELECT COUNT(*) FROM rounds r JOIN matches m ON m.id = r.match_id
+		WHERE m.match_id = ?`, matchID).Scan(&count)
+	return count, err
+}
+
 // InsertMatch inserts a new match and returns its database ID
 func (d *Database) InsertMatch(match *models.Match) (int64, error) {
-	result, err := d.db.Exec(`
+	return insertMatch(d.db, match)
+}
+
+func insertMatch(ex execer, match *models.Match) (int64, error) {
+	result, err := ex.Exec(`
 		INSERT INTO matches (
 			match_id, game_version, code_version, timestamp, match_type,
-			game_mode, map, recording_player, profile_id, team_score,
-			opponent_score, won, rounds_played, imported_at, file_path
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			game_mode, map, recording_player, profile_id, recording_team_index,
+			team_score, opponent_score, won, rounds_played, imported_at, file_path
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		match.MatchID, match.GameVersion, match.CodeVersion, match.Timestamp,
 		match.MatchType, match.GameMode, match.Map, match.RecordingPlayer,
-		match.ProfileID, match.TeamScore, match.OpponentScore, match.Won,
+		match.ProfileID, match.RecordingTeamIndex, match.TeamScore, match.OpponentScore, match.Won,
 		match.RoundsPlayed, time.Now(), match.FilePath,
 	)
 	if err != nil {
@@ -268,7 +152,11 @@ func (d *Database) InsertMatch(match *models.Match) (int64, error) {
 
 // InsertRound inserts a new round
 func (d *Database) InsertRound(round *models.Round) (int64, error) {
-	result, err := d.db.Exec(`
+	return insertRound(d.db, round)
+}
+
+func insertRound(ex execer, round *models.Round) (int64, error) {
+	result, err := ex.Exec(`
 		INSERT INTO rounds (
 			match_id, round_number, site, team_role, won,
 			win_condition, team_score, opponent_score
@@ -284,19 +172,40 @@ func (d *Database) InsertRound(round *models.Round) (int64, error) {
 
 // InsertPlayer inserts a player record
 func (d *Database) InsertPlayer(player *models.Player) error {
-	_, err := d.db.Exec(`
+	return insertPlayer(d.db, player)
+}
+
+func insertPlayer(ex execer, player *models.Player) error {
+	_, err := ex.Exec(`
 		INSERT INTO players (
 			round_id, match_id, profile_id, username, team_index, operator, spawn
 		) VALUES (?, ?, ?, ?, ?, ?, ?)`,
 		player.RoundID, player.MatchID, player.ProfileID, player.Username,
 		player.TeamIndex, player.Operator, player.Spawn,
 	)
+	if err != nil || player.ProfileID == "" {
+		return err
+	}
+
+	// Remember the name this profile played under, dated by the match
+	_, err = ex.Exec(`
+		INSERT INTO player_aliases (profile_id, username, first_seen, last_seen)
+		SELECT ?, ?, timestamp, timestamp FROM matches WHERE id = ?
+		ON CONFLICT (profile_id, username) DO UPDATE SET
+			first_seen = MIN(first_seen, excluded.first_seen),
+			last_seen = MAX(last_seen, excluded.last_seen)`,
+		player.ProfileID, player.Username, player.MatchID,
+	)
 	return err
 }
 
 // InsertEvent inserts a match event
 func (d *Database) InsertEvent(event *models.MatchEvent) error {
-	_, err := d.db.Exec(`
+	return insertEvent(d.db, event)
+}
+
+func insertEvent(ex execer, event *models.MatchEvent) error {
+	_, err := ex.Exec(`
 		INSERT INTO match_events (
 			round_id, match_id, event_type, time, time_in_seconds,
 			username, target, headshot, message
@@ -310,39 +219,64 @@ func (d *Database) InsertEvent(event *models.MatchEvent) error {
 
 // InsertPlayerRoundStats inserts player stats for a round
 func (d *Database) InsertPlayerRoundStats(stats *models.PlayerRoundStats) error {
-	_, err := d.db.Exec(`
+	return insertPlayerRoundStats(d.db, stats)
+}
+
+func insertPlayerRoundStats(ex execer, stats *models.PlayerRoundStats) error {
+	_, err := ex.Exec(`
 		INSERT INTO player_round_stats (
-			round_id, match_id, username, team_index, operator,
+			round_id, match_id, profile_id, username, team_index, operator,
 			kills, died, assists, headshots, headshot_percentage,
 			entry_kill, entry_death,
 			defuser_plants, defuser_defuses, defuser_pickups, plant_denials,
 			clutch_attempts, clutch_wins, clutch_1v1, clutch_1v2, clutch_1v3, clutch_1v4, clutch_1v5,
 			double_kills, triple_kills, quad_kills, ace,
-			trade_kills, trade_deaths, survival_time, survived
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		stats.RoundID, stats.MatchID, stats.Username, stats.TeamIndex,
+			trade_kills, trade_deaths, survival_time, survived, kost, rating,
+			analysis_version
+		) VALUES (?, ?, COALESCE(NULLIF(?, ''), (
+			SELECT profile_id FROM players WHERE round_id = ? AND username = ? LIMIT 1
+		), ''), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		stats.RoundID, stats.MatchID, stats.ProfileID, stats.RoundID, stats.Username, stats.Username, stats.TeamIndex,
 		stats.Operator, stats.Kills, stats.Died, stats.Assists,
 		stats.Headshots, stats.HeadshotPercentage, stats.EntryKill, stats.EntryDeath,
 		stats.DefuserPlants, stats.DefuserDefuses, stats.DefuserPickups, stats.PlantDenials,
 		stats.ClutchAttempts, stats.ClutchWins, stats.Clutch1v1, stats.Clutch1v2, stats.Clutch1v3, stats.Clutch1v4, stats.Clutch1v5,
 		stats.DoubleKills, stats.TripleKills, stats.QuadKills, stats.Ace,
-		stats.TradeKills, stats.TradeDeaths, stats.SurvivalTime, stats.Survived,
+		stats.TradeKills, stats.TradeDeaths, stats.SurvivalTime, stats.Survived, stats.KOST, stats.Rating,
+		stats.AnalysisVersion,
 	)
 	return err
 }
 
+// GetOutdatedMatchIDs returns the matches with no player round stats or any
+// computed by an analysis version older than version
+func (d *Database) GetOutdatedMatchIDs(version int) ([]int64, error) {
+	rows, err := d.db.Query(`
+		SELECT m.id FROM matches m
+		LEFT JOIN player_round_stats s ON s.match_id = m.id
+		GROUP BY m.id
+		HAVING COUNT(s.id) = 0 OR MIN(s.analysis_version) < ?
+		ORDER BY m.id
+	`, version)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // GetPlayerRoundStatsByRound returns all player stats for a round
 func (d *Database) GetPlayerRoundStatsByRound(roundID int64) ([]models.PlayerRoundStats, error) {
-	rows, err := d.db.Query(`
-		SELECT id, round_id, match_id, username, team_index, operator,
-		       kills, died, assists, headshots, headshot_percentage,
-		       entry_kill, entry_death,
-		       defuser_plants, defuser_defuses, defuser_pickups, plant_denials,
-		       clutch_attempts, clutch_wins, clutch_1v1, clutch_1v2, clutch_1v3, clutch_1v4, clutch_1v5,
-		       double_kills, triple_kills, quad_kills, ace,
-		       trade_kills, trade_deaths, survival_time, survived
-		FROM player_round_stats WHERE round_id = ? ORDER BY team_index, kills DESC
-	`, roundID)
+	rows, err := d.db.Query(playerRoundStatsSelect+" WHERE round_id = ? ORDER BY team_index, kills DESC", roundID)
 	if err != nil {
 		return nil, err
 	}
@@ -350,36 +284,47 @@ func (d *Database) GetPlayerRoundStatsByRound(roundID int64) ([]models.PlayerRou
 
 	var stats []models.PlayerRoundStats
 	for rows.Next() {
-		var s models.PlayerRoundStats
-		err := rows.Scan(
-			&s.ID, &s.RoundID, &s.MatchID, &s.Username, &s.TeamIndex,
-			&s.Operator, &s.Kills, &s.Died, &s.Assists, &s.Headshots,
-			&s.HeadshotPercentage, &s.EntryKill, &s.EntryDeath,
-			&s.DefuserPlants, &s.DefuserDefuses, &s.DefuserPickups, &s.PlantDenials,
-			&s.ClutchAttempts, &s.ClutchWins, &s.Clutch1v1, &s.Clutch1v2, &s.Clutch1v3, &s.Clutch1v4, &s.Clutch1v5,
-			&s.DoubleKills, &s.TripleKills, &s.QuadKills, &s.Ace,
-			&s.TradeKills, &s.TradeDeaths, &s.SurvivalTime, &s.Survived,
-		)
+		s, err := scanPlayerRoundStats(rows)
 		if err != nil {
 			return nil, err
 		}
-		stats = append(stats, s)
+		stats = append(stats, *s)
 	}
 	return stats, nil
 }
 
+const playerRoundStatsSelect = `
+	SELECT id, round_id, match_id, profile_id, username, team_index, operator,
+	       kills, died, assists, headshots, headshot_percentage,
+	       entry_kill, entry_death,
+	       defuser_plants, defuser_defuses, defuser_pickups, plant_denials,
+	       clutch_attempts, clutch_wins, clutch_1v1, clutch_1v2, clutch_1v3, clutch_1v4, clutch_1v5,
+	       double_kills, triple_kills, quad_kills, ace,
+	       trade_kills, trade_deaths, survival_time, survived, kost, rating,
+	       analysis_version
+	FROM player_round_stats`
+
+func scanPlayerRoundStats(row rowScanner) (*models.PlayerRoundStats, error) {
+	var s models.PlayerRoundStats
+	err := row.Scan(
+		&s.ID, &s.RoundID, &s.MatchID, &s.ProfileID, &s.Username, &s.TeamIndex,
+		&s.Operator, &s.Kills, &s.Died, &s.Assists, &s.Headshots,
+		&s.HeadshotPercentage, &s.EntryKill, &s.EntryDeath,
+		&s.DefuserPlants, &s.DefuserDefuses, &s.DefuserPickups, &s.PlantDenials,
+		&s.ClutchAttempts, &s.ClutchWins, &s.Clutch1v1, &s.Clutch1v2, &s.Clutch1v3, &s.Clutch1v4, &s.Clutch1v5,
+		&s.DoubleKills, &s.TripleKills, &s.QuadKills, &s.Ace,
+		&s.TradeKills, &s.TradeDeaths, &s.SurvivalTime, &s.Survived, &s.KOST, &s.Rating,
+		&s.AnalysisVersion,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
 // GetPlayerRoundStatsByMatch returns all player stats for a match (all rounds)
 func (d *Database) GetPlayerRoundStatsByMatch(matchID int64) ([]models.PlayerRoundStats, error) {
-	rows, err := d.db.Query(`
-		SELECT id, round_id, match_id, username, team_index, operator,
-		       kills, died, assists, headshots, headshot_percentage,
-		       entry_kill, entry_death,
-		       defuser_plants, defuser_defuses, defuser_pickups, plant_denials,
-		       clutch_attempts, clutch_wins, clutch_1v1, clutch_1v2, clutch_1v3, clutch_1v4, clutch_1v5,
-		       double_kills, triple_kills, quad_kills, ace,
-		       trade_kills, trade_deaths, survival_time, survived
-		FROM player_round_stats WHERE match_id = ? ORDER BY round_id, team_index, kills DESC
-	`, matchID)
+	rows, err := d.db.Query(playerRoundStatsSelect+" WHERE match_id = ? ORDER BY round_id, team_index, kills DESC", matchID)
 	if err != nil {
 		return nil, err
 	}
@@ -387,32 +332,18 @@ func (d *Database) GetPlayerRoundStatsByMatch(matchID int64) ([]models.PlayerRou
 
 	var stats []models.PlayerRoundStats
 	for rows.Next() {
-		var s models.PlayerRoundStats
-		err := rows.Scan(
-			&s.ID, &s.RoundID, &s.MatchID, &s.Username, &s.TeamIndex,
-			&s.Operator, &s.Kills, &s.Died, &s.Assists, &s.Headshots,
-			&s.HeadshotPercentage, &s.EntryKill, &s.EntryDeath,
-			&s.DefuserPlants, &s.DefuserDefuses, &s.DefuserPickups, &s.PlantDenials,
-			&s.ClutchAttempts, &s.ClutchWins, &s.Clutch1v1, &s.Clutch1v2, &s.Clutch1v3, &s.Clutch1v4, &s.Clutch1v5,
-			&s.DoubleKills, &s.TripleKills, &s.QuadKills, &s.Ace,
-			&s.TradeKills, &s.TradeDeaths, &s.SurvivalTime, &s.Survived,
-		)
+		s, err := scanPlayerRoundStats(rows)
 		if err != nil {
 			return nil, err
 		}
-		stats = append(stats, s)
+		stats = append(stats, *s)
 	}
 	return stats, nil
 }
 
 // GetAllMatches returns all matches ordered by timestamp descending
 func (d *Database) GetAllMatches() ([]models.Match, error) {
-	rows, err := d.db.Query(`
-		SELECT id, match_id, game_version, code_version, timestamp, match_type,
-		       game_mode, map, recording_player, profile_id, team_score,
-		       opponent_score, won, rounds_played, imported_at, file_path
-		FROM matches ORDER BY timestamp DESC
-	`)
+	rows, err := d.db.Query(matchSelect + " ORDER BY timestamp DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -420,24 +351,43 @@ func (d *Database) GetAllMatches() ([]models.Match, error) {
 
 	var matches []models.Match
 	for rows.Next() {
-		var m models.Match
-		err := rows.Scan(
-			&m.ID, &m.MatchID, &m.GameVersion, &m.CodeVersion, &m.Timestamp,
-			&m.MatchType, &m.GameMode, &m.Map, &m.RecordingPlayer, &m.ProfileID,
-			&m.TeamScore, &m.OpponentScore, &m.Won, &m.RoundsPlayed,
-			&m.ImportedAt, &m.FilePath,
-		)
+		m, err := scanMatch(rows)
 		if err != nil {
 			return nil, err
 		}
-		matches = append(matches, m)
+		matches = append(matches, *m)
 	}
 	return matches, nil
 }
 
+// GetMatch returns a single match by its database ID
+func (d *Database) GetMatch(id int64) (*models.Match, error) {
+	return scanMatch(d.db.QueryRow(matchSelect+" WHERE id = ?", id))
+}
+
+const matchSelect = `
+	SELECT id, match_id, game_version, code_version, timestamp, match_type,
+	       game_mode, map, recording_player, profile_id, recording_team_index,
+	       team_score, opponent_score, won, rounds_played, imported_at, file_path
+	FROM matches`
+
+func scanMatch(row rowScanner) (*models.Match, error) {
+	var m models.Match
+	err := row.Scan(
+		&m.ID, &m.MatchID, &m.GameVersion, &m.CodeVersion, &m.Timestamp,
+		&m.MatchType, &m.GameMode, &m.Map, &m.RecordingPlayer, &m.ProfileID,
+		&m.RecordingTeamIndex, &m.TeamScore, &m.OpponentScore, &m.Won,
+		&m.RoundsPlayed, &m.ImportedAt, &m.FilePath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
 // GetMatchesByFilter returns matches matching the given criteria
 func (d *Database) GetMatchesByFilter(matchType, mapName string, won *bool) ([]models.Match, error) {
-	query := "SELECT id, match_id, game_version, code_version, timestamp, match_type, game_mode, map, recording_player, profile_id, team_score, opponent_score, won, rounds_played, imported_at, file_path FROM matches WHERE 1=1"
+	query := matchSelect + " WHERE 1=1"
 	args := []interface{}{}
 
 	if matchType != "" && matchType != "All" {
@@ -463,24 +413,22 @@ func (d *Database) GetMatchesByFilter(matchType, mapName string, won *bool) ([]m
 
 	var matches []models.Match
 	for rows.Next() {
-		var m models.Match
-		err := rows.Scan(
-			&m.ID, &m.MatchID, &m.GameVersion, &m.CodeVersion, &m.Timestamp,
-			&m.MatchType, &m.GameMode, &m.Map, &m.RecordingPlayer, &m.ProfileID,
-			&m.TeamScore, &m.OpponentScore, &m.Won, &m.RoundsPlayed,
-			&m.ImportedAt, &m.FilePath,
-		)
+		m, err := scanMatch(rows)
 		if err != nil {
 			return nil, err
 		}
-		matches = append(matches, m)
+		matches = append(matches, *m)
 	}
 	return matches, nil
 }
 
 // GetRoundsByMatch returns all rounds for a given match
 func (d *Database) GetRoundsByMatch(matchID int64) ([]models.Round, error) {
-	rows, err := d.db.Query(`
+	return getRoundsByMatch(d.db, matchID)
+}
+
+func getRoundsByMatch(q querier, matchID int64) ([]models.Round, error) {
+	rows, err := q.Query(`
 		SELECT id, match_id, round_number, site, team_role, won, 
 		       win_condition, team_score, opponent_score
 		FROM rounds WHERE match_id = ? ORDER BY round_number
@@ -531,12 +479,14 @@ func (d *Database) GetPlayersByRound(roundID int64) ([]models.Player, error) {
 	return players, nil
 }
 
-// GetEventsByRound returns all events in a round
+// GetEventsByRound returns all events in a round in feed order. The timer
+// restarts once the defuser is planted, so the order can't be taken from
+// time_in_seconds.
 func (d *Database) GetEventsByRound(roundID int64) ([]models.MatchEvent, error) {
 	rows, err := d.db.Query(`
 		SELECT id, round_id, match_id, event_type, time, time_in_seconds,
 		       username, target, headshot, message
-		FROM match_events WHERE round_id = ? ORDER BY time_in_seconds DESC
+		FROM match_events WHERE round_id = ? ORDER BY id
 	`, roundID)
 	if err != nil {
 		return nil, err
@@ -558,8 +508,36 @@ func (d *Database) GetEventsByRound(roundID int64) ([]models.MatchEvent, error)
 	return events, nil
 }
 
-// GetMapStats returns aggregated stats per map
-func (d *Database) GetMapStats() ([]models.MapStats, error) {
+// GetRoundRaw returns the stored raw rounds of a match keyed by round ID.
+// Rounds imported before raw rounds were kept are missing from the map.
+func (d *Database) GetRoundRaw(matchID int64) (map[int64][]byte, error) {
+	return getRoundRaw(d.db, matchID)
+}
+
+func getRoundRaw(q querier, matchID int64) (map[int64][]byte, error) {
+	rows, err := q.Query("SELECT round_id, data FROM round_raw WHERE match_id = ?", matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	raw := make(map[int64][]byte)
+	for rows.Next() {
+		var roundID int64
+		var data []byte
+		if err := rows.Scan(&roundID, &data); err != nil {
+			return nil, err
+		}
+		raw[roundID] = data
+	}
+	return raw, rows.Err()
+}
+
+// GetMapStats returns aggregated stats per map, with round win rates split
+// by side. With a side ("Attack" or "Defense"), Played, Wins and Losses
+// count the recording team's rounds on that side instead of matches, and
+// maps never played on it are left out.
+func (d *Database) GetMapStats(side string) ([]models.MapStats, error) {
 	rows, err := d.db.Query(`
 		SELECT map, 
 		       COUNT(*) as played,
@@ -573,31 +551,105 @@ func (d *Database) GetMapStats() ([]models.MapStats, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
 	var stats []models.MapStats
+	index := make(map[string]int)
 	for rows.Next() {
 		var s models.MapStats
 		err := rows.Scan(&s.MapName, &s.Played, &s.Wins, &s.Losses, &s.AvgRounds)
 		if err != nil {
+			rows.Close()
 			return nil, err
 		}
-		if s.Played > 0 {
-			s.WinRate = float64(s.Wins) / float64(s.Played) * 100
-		}
+		index[s.MapName] = len(stats)
 		stats = append(stats, s)
 	}
-	return stats, nil
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = d.db.Query(`
+		SELECT m.map,
+		       SUM(CASE WHEN r.team_role = 'Attack' THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN r.team_role = 'Attack' AND r.won THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN r.team_role = 'Defense' THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN r.team_role = 'Defense' AND r.won THEN 1 ELSE 0 END)
+		FROM rounds r
+		JOIN matches m ON m.id = r.match_id
+		GROUP BY m.map
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var split models.MapStats
+		err := rows.Scan(&name, &split.AttackRounds, &split.AttackWins, &split.DefenseRounds, &split.DefenseWins)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := index[name]; ok {
+			s := &stats[i]
+			s.AttackRounds, s.AttackWins = split.AttackRounds, split.AttackWins
+			s.DefenseRounds, s.DefenseWins = split.DefenseRounds, split.DefenseWins
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var result []models.MapStats
+	for _, s := range stats {
+		switch side {
+		case "Attack":
+			s.Played, s.Wins = s.AttackRounds, s.AttackWins
+			s.Losses = s.Played - s.Wins
+		case "Defense":
+			s.Played, s.Wins = s.DefenseRounds, s.DefenseWins
+			s.Losses = s.Played - s.Wins
+		}
+		if s.Played == 0 {
+			continue
+		}
+		s.WinRate = float64(s.Wins) / float64(s.Played) * 100
+		if s.AttackRounds > 0 {
+			s.AttackWinRate = float64(s.AttackWins) / float64(s.AttackRounds) * 100
+		}
+		if s.DefenseRounds > 0 {
+			s.DefenseWinRate = float64(s.DefenseWins) / float64(s.DefenseRounds) * 100
+		}
+		result = append(result, s)
+	}
+	if filterSet(side) {
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i].Played > result[j].Played
+		})
+	}
+	return result, nil
 }
 
-// GetOverallStats returns overall match statistics
-func (d *Database) GetOverallStats() (played, wins, losses int, winRate float64, err error) {
-	err = d.db.QueryRow(`
-		SELECT COUNT(*) as played,
-		       SUM(CASE WHEN won THEN 1 ELSE 0 END) as wins,
-		       SUM(CASE WHEN NOT won THEN 1 ELSE 0 END) as losses
-		FROM matches
-	`).Scan(&played, &wins, &losses)
+// GetOverallStats returns overall match statistics. With a side ("Attack"
+// or "Defense") it counts the recording team's rounds on that side instead.
+func (d *Database) GetOverallStats(side string) (played, wins, losses int, winRate float64, err error) {
+	if filterSet(side) {
+		err = d.db.QueryRow(`
+			SELECT COUNT(*),
+			       COALESCE(SUM(CASE WHEN won THEN 1 ELSE 0 END), 0),
+			       COALESCE(SUM(CASE WHEN NOT won THEN 1 ELSE 0 END), 0)
+			FROM rounds
+			WHERE team_role = ?
+		`, side).Scan(&played, &wins, &losses)
+	} else {
+		err = d.db.QueryRow(`
+			SELECT COUNT(*) as played,
+			       SUM(CASE WHEN won THEN 1 ELSE 0 END) as wins,
+			       SUM(CASE WHEN NOT won THEN 1 ELSE 0 END) as losses
+			FROM matches
+		`).Scan(&played, &wins, &losses)
+	}
 	if err != nil {
 		return
 	}
@@ -626,29 +678,32 @@ func (d *Database) GetDistinctMaps() ([]string, error) {
 	return maps, nil
 }
 
-// GetClutchStats returns aggregated clutch statistics for all players
-func (d *Database) GetClutchStats() ([]models.ClutchStats, error) {
+// GetClutchStats returns aggregated clutch statistics for the recording player's team,
+// optionally only from rounds on one side ("Attack" or "Defense")
+func (d *Database) GetClutchStats(side string) ([]models.ClutchStats, error) {
+	where, args := sideWhere(side)
 	rows, err := d.db.Query(`
 		SELECT 
-			username,
+			s.profile_id,
+			`+currentNameExpr("s.profile_id", "s.username")+` as username,
 			SUM(CASE WHEN clutch_1v1 THEN 1 ELSE 0 END) as clutch_1v1,
-			SUM(CASE WHEN clutch_1v1 AND survived THEN 1 ELSE 0 END) as clutch_1v1_won,
+			SUM(CASE WHEN clutch_1v1 AND clutch_wins > 0 THEN 1 ELSE 0 END) as clutch_1v1_won,
 			SUM(CASE WHEN clutch_1v2 THEN 1 ELSE 0 END) as clutch_1v2,
-			SUM(CASE WHEN clutch_1v2 AND survived THEN 1 ELSE 0 END) as clutch_1v2_won,
+			SUM(CASE WHEN clutch_1v2 AND clutch_wins > 0 THEN 1 ELSE 0 END) as clutch_1v2_won,
 			SUM(CASE WHEN clutch_1v3 THEN 1 ELSE 0 END) as clutch_1v3,
-			SUM(CASE WHEN clutch_1v3 AND survived THEN 1 ELSE 0 END) as clutch_1v3_won,
+			SUM(CASE WHEN clutch_1v3 AND clutch_wins > 0 THEN 1 ELSE 0 END) as clutch_1v3_won,
 			SUM(CASE WHEN clutch_1v4 THEN 1 ELSE 0 END) as clutch_1v4,
-			SUM(CASE WHEN clutch_1v4 AND survived THEN 1 ELSE 0 END) as clutch_1v4_won,
+			SUM(CASE WHEN clutch_1v4 AND clutch_wins > 0 THEN 1 ELSE 0 END) as clutch_1v4_won,
 			SUM(CASE WHEN clutch_1v5 THEN 1 ELSE 0 END) as clutch_1v5,
-			SUM(CASE WHEN clutch_1v5 AND survived THEN 1 ELSE 0 END) as clutch_1v5_won,
+			SUM(CASE WHEN clutch_1v5 AND clutch_wins > 0 THEN 1 ELSE 0 END) as clutch_1v5_won,
 			SUM(clutch_attempts) as total_attempts,
 			SUM(clutch_wins) as total_wins
-		FROM player_round_stats
-		WHERE team_index = 0
-		GROUP BY username
+		`+filteredRoundStatsFrom+`
+		WHERE s.team_index = m.recording_team_index`+where+`
+		GROUP BY `+playerKeyExpr+`
 		HAVING total_attempts > 0
 		ORDER BY total_wins DESC
-	`)
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -659,7 +714,7 @@ func (d *Database) GetClutchStats() ([]models.ClutchStats, error) {
 		var s models.ClutchStats
 		var totalAttempts, totalWins int
 		err := rows.Scan(
-			&s.Username,
+			&s.ProfileID, &s.Username,
 			&s.Clutch1v1, &s.Clutch1v1Won,
 			&s.Clutch1v2, &s.Clutch1v2Won,
 			&s.Clutch1v3, &s.Clutch1v3Won,
@@ -678,20 +733,23 @@ func (d *Database) GetClutchStats() ([]models.ClutchStats, error) {
 	return stats, nil
 }
 
-// GetDefuserStats returns aggregated defuser statistics for all players
-func (d *Database) GetDefuserStats() ([]models.DefuserStats, error) {
+// GetDefuserStats returns aggregated defuser statistics for the recording player's team,
+// optionally only from rounds on one side
+func (d *Database) GetDefuserStats(side string) ([]models.DefuserStats, error) {
+	where, args := sideWhere(side)
 	rows, err := d.db.Query(`
 		SELECT 
-			username,
+			s.profile_id,
+			`+currentNameExpr("s.profile_id", "s.username")+` as username,
 			SUM(defuser_plants) as plants,
 			SUM(defuser_defuses) as defuses,
 			SUM(plant_denials) as plant_denials
-		FROM player_round_stats
-		WHERE team_index = 0
-		GROUP BY username
+		`+filteredRoundStatsFrom+`
+		WHERE s.team_index = m.recording_team_index`+where+`
+		GROUP BY `+playerKeyExpr+`
 		HAVING (plants > 0 OR defuses > 0 OR plant_denials > 0)
 		ORDER BY plants DESC
-	`)
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -700,7 +758,7 @@ func (d *Database) GetDefuserStats() ([]models.DefuserStats, error) {
 	var stats []models.DefuserStats
 	for rows.Next() {
 		var s models.DefuserStats
-		err := rows.Scan(&s.Username, &s.Plants, &s.Defuses, &s.PlantDenials)
+		err := rows.Scan(&s.ProfileID, &s.Username, &s.Plants, &s.Defuses, &s.PlantDenials)
 		if err != nil {
 			return nil, err
 		}
@@ -713,13 +771,89 @@ func (d *Database) GetDefuserStats() ([]models.DefuserStats, error) {
 	return stats, nil
 }
 
+// GetKOSTStats returns KOST and average survival time for players on the recording team,
+// optionally only from rounds on one side
+func (d *Database) GetKOSTStats(side string) ([]models.KOSTStats, error) {
+	where, args := sideWhere(side)
+	rows, err := d.db.Query(`
+		SELECT
+			s.profile_id,
+			`+currentNameExpr("s.profile_id", "s.username")+` as username,
+			COUNT(*) as rounds,
+			SUM(kost) as kost_rounds,
+			AVG(survival_time) as avg_survival
+		`+filteredRoundStatsFrom+`
+		WHERE s.team_index = m.recording_team_index`+where+`
+		GROUP BY `+playerKeyExpr+`
+		ORDER BY rounds DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []models.KOSTStats
+	for rows.Next() {
+		var s models.KOSTStats
+		err := rows.Scan(&s.ProfileID, &s.Username, &s.Rounds, &s.KOSTRounds, &s.AvgSurvivalTime)
+		if err != nil {
+			return nil, err
+		}
+		if s.Rounds > 0 {
+			s.KOSTRate = float64(s.KOSTRounds) / float64(s.Rounds) * 100
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// GetRatingStats returns the average SiegeScope Rating per round and the
+// best match for players on the recording team, optionally only from rounds
+// on one side, best rated first
+func (d *Database) GetRatingStats(side string) ([]models.RatingStats, error) {
+	where, args := sideWhere(side)
+	rows, err := d.db.Query(`
+		SELECT
+			p.profile_id,
+			`+currentNameExpr("p.profile_id", "p.username")+` as username,
+			SUM(p.rounds) as rounds,
+			SUM(p.total) / SUM(p.rounds) as rating,
+			MAX(p.total / p.rounds) as best
+		FROM (
+			SELECT `+playerKeyExpr+` as player, s.profile_id, s.username,
+			       COUNT(*) as rounds, SUM(s.rating) as total
+			`+filteredRoundStatsFrom+`
+			WHERE s.team_index = m.recording_team_index`+where+`
+			GROUP BY player, s.match_id
+		) p
+		GROUP BY p.player
+		ORDER BY rating DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []models.RatingStats
+	for rows.Next() {
+		var s models.RatingStats
+		if err := rows.Scan(&s.ProfileID, &s.Username, &s.Rounds, &s.Rating, &s.Best); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
 // GetSettings returns current settings
 func (d *Database) GetSettings() (*models.Settings, error) {
 	var s models.Settings
 	err := d.db.QueryRow(`
-		SELECT id, replay_folder, auto_import, theme, start_minimized, start_with_system
+		SELECT id, COALESCE(replay_folder, ''), auto_import, theme, start_minimized, start_with_system, watch_polling,
+		       trade_window, multi_kill_window
 		FROM settings WHERE id = 1
-	`).Scan(&s.ID, &s.ReplayFolder, &s.AutoImport, &s.Theme, &s.StartMinimized, &s.StartWithSystem)
+	`).Scan(&s.ID, &s.ReplayFolder, &s.AutoImport, &s.Theme, &s.StartMinimized, &s.StartWithSystem, &s.WatchPolling,
+		&s.Analysis.TradeWindow, &s.Analysis.MultiKillWindow)
 	if err != nil {
 		return nil, err
 	}
@@ -731,18 +865,76 @@ func (d *Database) UpdateSettings(s *models.Settings) error {
 	_, err := d.db.Exec(`
 		UPDATE settings SET 
 			replay_folder = ?, auto_import = ?, theme = ?,
-			start_minimized = ?, start_with_system = ?
+			start_minimized = ?, start_with_system = ?, watch_polling = ?,
+			trade_window = ?, multi_kill_window = ?
 		WHERE id = 1
-	`, s.ReplayFolder, s.AutoImport, s.Theme, s.StartMinimized, s.StartWithSystem)
+	`, s.ReplayFolder, s.AutoImport, s.Theme, s.StartMinimized, s.StartWithSystem, s.WatchPolling,
+		s.Analysis.TradeWindow, s.Analysis.MultiKillWindow)
 	return err
 }
 
-// DeleteMatch removes a match and all its related data
+// DeleteMatch removes a match and all its related data. Its folder is
+// dropped from the import ledger so it can be imported again.
 func (d *Database) DeleteMatch(matchID int64) error {
-	_, err := d.db.Exec("DELETE FROM matches WHERE id = ?", matchID)
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM import_ledger WHERE match_id = (SELECT match_id FROM matches WHERE id = ?)", matchID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM matches WHERE id = ?", matchID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ClearAllMatches removes every match along with its rounds, players, events,
+// per-round stats, raw rounds and the import ledger in one transaction, then compacts the
+// file. Settings are kept.
+func (d *Database) ClearAllMatches() error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Children first so this works even if foreign keys are disabled
+	tables := []string{"round_raw", "player_round_stats", "player_aliases", "match_events", "players", "rounds", "matches", "import_ledger"}
+	for _, table := range tables {
+		if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM sqlite_sequence WHERE name IN ('player_round_stats', 'match_events', 'players', 'rounds', 'matches')"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// VACUUM cannot run inside a transaction
+	_, err = d.db.Exec("VACUUM")
 	return err
 }
 
+// Backup writes a consistent copy of replays.db to the backups folder and returns its path
+func (d *Database) Backup() (string, error) {
+	backupDir := filepath.Join(filepath.Dir(d.path), "backups")
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", err
+	}
+
+	backupPath := filepath.Join(backupDir, fmt.Sprintf("replays-%s.db", time.Now().Format("20060102-150405")))
+	if _, err := d.db.Exec("VACUUM INTO ?", backupPath); err != nil {
+		return "", fmt.Errorf("failed to back up database: %w", err)
+	}
+	return backupPath, nil
+}
+
 // GetMatchCount returns total number of matches
 func (d *Database) GetMatchCount() (int, error) {
 	var count int
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// openTestDB opens a fresh, fully migrated database in a temporary directory
+func openTestDB(t *testing.T) *Database {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "replays.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestGetOutdatedMatchIDs(t *testing.T) {
+	db := openTestDB(t)
+	current := seedMatch(t, db, "current", 1)
+	old := seedMatch(t, db, "old", 1)
+	noStats := seedMatch(t, db, "no-stats", 1)
+
+	if _, err := db.db.Exec("UPDATE player_round_stats SET analysis_version = 2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.db.Exec("UPDATE player_round_stats SET analysis_version = 1 WHERE match_id = ? AND username = 'opponent'", old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.db.Exec("DELETE FROM player_round_stats WHERE match_id = ?", noStats); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := db.GetOutdatedMatchIDs(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{old, noStats}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("GetOutdatedMatchIDs(2) = %v, want %v (match %d is current)", ids, want, current)
+	}
+}
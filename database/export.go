@@ -0,0 +1,302 @@
+package database
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"r6-replay-recorder/models"
+)
+
+// ArchiveFormat identifies a SiegeScope JSON archive
+const ArchiveFormat = "siegescope-archive"
+
+// ArchiveVersion is the archive layout written by ExportJSON.
+// Bump it whenever a field is renamed, removed or changes meaning.
+//
+//	1: initial layout
+//	2: match and round results are from the recording player's team
+//	   (recordingTeamIndex) instead of always team 0
+//	3: rounds carry their compressed decoded replay (raw), so they can be
+//	   re-analyzed without the .rec files, and every player alias is listed
+//	   after the matches (aliases)
+const ArchiveVersion = 3
+
+// ArchiveHeader is the top-level metadata of a JSON archive
+type ArchiveHeader struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+}
+
+// ArchiveMatch is a match with all of its rounds as stored in an archive
+type ArchiveMatch struct {
+	models.Match
+	Rounds []ArchiveRound `json:"rounds"`
+}
+
+// ArchiveRound is a round with its players, events and per-player stats
+type ArchiveRound struct {
+	models.Round
+	Players          []models.Player           `json:"players"`
+	Events           []models.MatchEvent       `json:"events"`
+	PlayerRoundStats []models.PlayerRoundStats `json:"playerRoundStats"`
+	// Raw is the round's round_raw data, missing for rounds imported before
+	// raw rounds were kept
+	Raw []byte `json:"raw,omitempty"`
+}
+
+// ExportJSON writes every match in the database to w as a versioned JSON archive.
+// The whole export reads from one transaction, so imports, deletions and
+// re-analysis running at the same time can't leave it with a mix of old and
+// new rows. Matches are loaded and encoded one at a time so the library never
+// has to fit in memory. It returns the number of matches written.
+func (d *Database) ExportJSON(w io.Writer) (int, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin export transaction: %w", err)
+	}
+	// Nothing is written, so the read transaction is always rolled back
+	defer tx.Rollback()
+
+	ids, err := getMatchIDs(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	header, err := json.Marshal(ArchiveHeader{
+		Format:     ArchiveFormat,
+		Version:    ArchiveVersion,
+		ExportedAt: time.Now(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Open the header object and splice the matches array into it
+	if _, err := bw.Write(header[:len(header)-1]); err != nil {
+		return 0, err
+	}
+	if _, err := bw.WriteString(`,"matches":[`); err != nil {
+		return 0, err
+	}
+
+	exported := 0
+	for i, id := range ids {
+		archived, err := loadArchiveMatch(tx, id)
+		if err != nil {
+			return exported, fmt.Errorf("failed to export match %d: %w", id, err)
+		}
+
+		data, err := json.Marshal(archived)
+		if err != nil {
+			return exported, fmt.Errorf("failed to encode match %s: %w", archived.MatchID, err)
+		}
+
+		if i > 0 {
+			if err := bw.WriteByte(','); err != nil {
+				return exported, err
+			}
+		}
+		if _, err := bw.Write(data); err != nil {
+			return exported, err
+		}
+		exported++
+	}
+
+	// Aliases outlive deleted matches, so they are listed on their own
+	aliases, err := getAllAliases(tx)
+	if err != nil {
+		return exported, fmt.Errorf("failed to export player aliases: %w", err)
+	}
+	data, err := json.Marshal(EmptyIfNil(aliases))
+	if err != nil {
+		return exported, err
+	}
+	if _, err := bw.WriteString(`],"aliases":`); err != nil {
+		return exported, err
+	}
+	if _, err := bw.Write(data); err != nil {
+		return exported, err
+	}
+	if _, err := bw.WriteString("}\n"); err != nil {
+		return exported, err
+	}
+
+	return exported, bw.Flush()
+}
+
+// getMatchIDs returns the database IDs of all matches, oldest first
+func getMatchIDs(q querier) ([]int64, error) {
+	rows, err := q.Query("SELECT id FROM matches ORDER BY timestamp, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// loadArchiveMatch reads a single match and all of its child rows. Each
+// child table is read once for the whole match and split up by round.
+func loadArchiveMatch(q querier, id int64) (*ArchiveMatch, error) {
+	match, err := scanMatch(q.QueryRow(matchSelect+" WHERE id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+
+	rounds, err := getRoundsByMatch(q, id)
+	if err != nil {
+		return nil, err
+	}
+	players, err := getPlayersByMatch(q, id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := getEventsByMatch(q, id)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := getPlayerRoundStatsByMatch(q, id)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := getRoundRaw(q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	archived := &ArchiveMatch{
+		Match:  *match,
+		Rounds: make([]ArchiveRound, 0, len(rounds)),
+	}
+
+	for _, round := range rounds {
+		archived.Rounds = append(archived.Rounds, ArchiveRound{
+			Round:            round,
+			Players:          EmptyIfNil(players[round.ID]),
+			Events:           EmptyIfNil(events[round.ID]),
+			PlayerRoundStats: EmptyIfNil(stats[round.ID]),
+			Raw:              raw[round.ID],
+		})
+	}
+
+	return archived, nil
+}
+
+// getPlayersByMatch returns the players of every round of a match keyed by round ID
+func getPlayersByMatch(q querier, matchID int64) (map[int64][]models.Player, error) {
+	rows, err := q.Query(`
+		SELECT id, round_id, match_id, profile_id, username, team_index, operator, spawn
+		FROM players WHERE match_id = ? ORDER BY round_id, team_index, username
+	`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	players := make(map[int64][]models.Player)
+	for rows.Next() {
+		var p models.Player
+		err := rows.Scan(
+			&p.ID, &p.RoundID, &p.MatchID, &p.ProfileID, &p.Username,
+			&p.TeamIndex, &p.Operator, &p.Spawn,
+		)
+		if err != nil {
+			return nil, err
+		}
+		players[p.RoundID] = append(players[p.RoundID], p)
+	}
+	return players, rows.Err()
+}
+
+// getEventsByMatch returns the events of every round of a match keyed by
+// round ID, each in the order they were recorded
+func getEventsByMatch(q querier, matchID int64) (map[int64][]models.MatchEvent, error) {
+	rows, err := q.Query(`
+		SELECT id, round_id, match_id, event_type, time, time_in_seconds,
+		       username, target, headshot, message
+		FROM match_events WHERE match_id = ? ORDER BY id
+	`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make(map[int64][]models.MatchEvent)
+	for rows.Next() {
+		var e models.MatchEvent
+		err := rows.Scan(
+			&e.ID, &e.RoundID, &e.MatchID, &e.EventType, &e.Time,
+			&e.TimeInSeconds, &e.Username, &e.Target, &e.Headshot, &e.Message,
+		)
+		if err != nil {
+			return nil, err
+		}
+		events[e.RoundID] = append(events[e.RoundID], e)
+	}
+	return events, rows.Err()
+}
+
+// getPlayerRoundStatsByMatch returns the player stats of every round of a
+// match keyed by round ID
+func getPlayerRoundStatsByMatch(q querier, matchID int64) (map[int64][]models.PlayerRoundStats, error) {
+	rows, err := q.Query(playerRoundStatsSelect+" WHERE match_id = ? ORDER BY round_id, team_index, kills DESC", matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make(map[int64][]models.PlayerRoundStats)
+	for rows.Next() {
+		s, err := scanPlayerRoundStats(rows)
+		if err != nil {
+			return nil, err
+		}
+		stats[s.RoundID] = append(stats[s.RoundID], *s)
+	}
+	return stats, rows.Err()
+}
+
+// getAllAliases returns every username seen per profile
+func getAllAliases(q querier) ([]models.PlayerAlias, error) {
+	rows, err := q.Query(`
+		SELECT profile_id, username, first_seen, last_seen
+		FROM player_aliases ORDER BY profile_id, first_seen, username
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aliases []models.PlayerAlias
+	for rows.Next() {
+		var a models.PlayerAlias
+		if err := rows.Scan(&a.ProfileID, &a.Username, &a.FirstSeen, &a.LastSeen); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// EmptyIfNil returns s, or an empty slice if it is nil, so empty lists encode
+// as [] rather than null in archives and JSON output
+func EmptyIfNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,347 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"r6-replay-recorder/models"
+)
+
+// seedMatch writes a match with the given number of rounds, each with two
+// players, a kill, their stats and raw data, and returns its database ID
+func seedMatch(t *testing.T, db *Database, matchID string, rounds int) int64 {
+	t.Helper()
+	w, err := db.BeginMatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Rollback()
+
+	matchDBID, err := w.InsertMatch(&models.Match{
+		MatchID: matchID, Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Map: "Bank", RecordingPlayer: "recorder", ProfileID: "p1",
+		TeamScore: rounds, Won: true, RoundsPlayed: rounds,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= rounds; i++ {
+		roundDBID, err := w.InsertRound(&models.Round{
+			MatchID: matchDBID, RoundNumber: i, Site: "CEO Office", TeamRole: "Attack",
+			Won: true, WinCondition: "KilledOpponents", TeamScore: i,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []models.Player{
+			{ProfileID: "p1", Username: "recorder", TeamIndex: 0, Operator: "Ash"},
+			{ProfileID: "p2", Username: "opponent", TeamIndex: 1, Operator: "Mute"},
+		} {
+			p.RoundID, p.MatchID = roundDBID, matchDBID
+			if err := w.InsertPlayer(&p); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.InsertPlayerRoundStats(&models.PlayerRoundStats{
+				RoundID: roundDBID, MatchID: matchDBID, Username: p.Username,
+				TeamIndex: p.TeamIndex, Operator: p.Operator, Kills: 1 - p.TeamIndex, Died: p.TeamIndex == 1,
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.InsertEvent(&models.MatchEvent{
+			RoundID: roundDBID, MatchID: matchDBID, EventType: "Kill", Time: "2:00",
+			TimeInSeconds: 120, Username: "recorder", Target: "opponent",
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.InsertRoundRaw(roundDBID, matchDBID, []byte(fmt.Sprintf("raw %s %d", matchID, i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return matchDBID
+}
+
+func exportArchive(t *testing.T, db *Database) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := db.ExportJSON(&buf); err != nil {
+		t.Fatalf("ExportJSON: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveKeepsRawRoundsAndAliases(t *testing.T) {
+	src := openTestDB(t)
+	seedMatch(t, src, "m1", 2)
+	// An alias whose match has since been deleted
+	if _, err := src.db.Exec(`INSERT INTO player_aliases (profile_id, username, first_seen, last_seen)
+		VALUES ('p2', 'old name', ?, ?)`, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := openTestDB(t)
+	summary, err := dst.ImportJSON(bytes.NewReader(exportArchive(t, src)))
+	if err != nil {
+		t.Fatalf("ImportJSON: %v", err)
+	}
+	if summary.RawRoundsAdded != 2 {
+		t.Errorf("RawRoundsAdded = %d, want 2", summary.RawRoundsAdded)
+	}
+
+	matches, err := dst.GetAllMatches()
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("GetAllMatches = %d matches, %v", len(matches), err)
+	}
+	rounds, err := dst.GetRoundsByMatch(matches[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := dst.GetRoundRaw(matches[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range rounds {
+		if want := fmt.Sprintf("raw m1 %d", r.RoundNumber); string(raw[r.ID]) != want {
+			t.Errorf("round %d raw = %q, want %q", r.RoundNumber, raw[r.ID], want)
+		}
+	}
+
+	want, err := src.GetPlayerAliases("p2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := dst.GetPlayerAliases("p2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != 2 {
+		t.Fatalf("source has %d aliases of p2, want 2", len(want))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("aliases = %+v, want %+v", got, want)
+	}
+}
+
+// writeHook calls before on the first write, then writes to w
+type writeHook struct {
+	w      bytes.Buffer
+	before func()
+}
+
+func (h *writeHook) Write(p []byte) (int, error) {
+	if h.before != nil {
+		h.before()
+		h.before = nil
+	}
+	return h.w.Write(p)
+}
+
+func TestImportDuringExport(t *testing.T) {
+	db := openTestDB(t)
+	seedMatch(t, db, "m1", 1)
+
+	// The export's read transaction is still open when it first writes out
+	hook := &writeHook{before: func() { seedMatch(t, db, "m2", 1) }}
+	n, err := db.ExportJSON(hook)
+	if err != nil {
+		t.Fatalf("ExportJSON: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("exported %d matches, want the 1 there when the export started", n)
+	}
+	if count, err := db.GetMatchCount(); err != nil || count != 2 {
+		t.Errorf("GetMatchCount = %d, %v; want 2", count, err)
+	}
+}
+
+// tableCounts returns the number of rows in every table an archive carries
+func tableCounts(t *testing.T, db *Database) map[string]int {
+	t.Helper()
+	counts := make(map[string]int)
+	for _, table := range []string{"matches", "rounds", "players", "match_events", "player_round_stats", "round_raw", "player_aliases"} {
+		var n int
+		if err := db.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		counts[table] = n
+	}
+	return counts
+}
+
+// decodeArchiveMatches returns an archive's matches without the database IDs
+// and import times, which differ between libraries
+func decodeArchiveMatches(t *testing.T, data []byte) []ArchiveMatch {
+	t.Helper()
+	var archive struct {
+		Matches []ArchiveMatch `json:"matches"`
+	}
+	if err := json.Unmarshal(data, &archive); err != nil {
+		t.Fatal(err)
+	}
+	for i := range archive.Matches {
+		m := &archive.Matches[i]
+		m.ID, m.ImportedAt = 0, time.Time{}
+		for j := range m.Rounds {
+			r := &m.Rounds[j]
+			r.ID, r.MatchID = 0, 0
+			for k := range r.Players {
+				r.Players[k].ID, r.Players[k].RoundID, r.Players[k].MatchID = 0, 0, 0
+			}
+			for k := range r.Events {
+				r.Events[k].ID, r.Events[k].RoundID, r.Events[k].MatchID = 0, 0, 0
+			}
+			for k := range r.PlayerRoundStats {
+				r.PlayerRoundStats[k].ID, r.PlayerRoundStats[k].RoundID, r.PlayerRoundStats[k].MatchID = 0, 0, 0
+			}
+		}
+	}
+	return archive.Matches
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	src := openTestDB(t)
+	seedMatch(t, src, "m1", 3)
+	seedMatch(t, src, "m2", 2)
+	archive := exportArchive(t, src)
+
+	dst := openTestDB(t)
+	summary, err := dst.ImportJSON(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("ImportJSON: %v", err)
+	}
+
+	want := ArchiveImportSummary{
+		MatchesAdded: 2, RoundsAdded: 5, PlayersAdded: 10, EventsAdded: 5, StatsAdded: 10,
+		RawRoundsAdded: 5, AliasesMerged: 2,
+	}
+	if !reflect.DeepEqual(*summary, want) {
+		t.Errorf("summary = %+v, want %+v", *summary, want)
+	}
+
+	if got, want := tableCounts(t, dst), tableCounts(t, src); !reflect.DeepEqual(got, want) {
+		t.Errorf("row counts after import = %v, want %v", got, want)
+	}
+
+	got, wantMatches := decodeArchiveMatches(t, exportArchive(t, dst)), decodeArchiveMatches(t, archive)
+	if !reflect.DeepEqual(got, wantMatches) {
+		t.Errorf("re-exported matches differ from the original archive:\n got:  %+v\n want: %+v", got, wantMatches)
+	}
+}
+
+func TestArchiveImportSkipsAndConflicts(t *testing.T) {
+	src := openTestDB(t)
+	seedMatch(t, src, "m1", 2)
+	seedMatch(t, src, "m2", 2)
+	archive := exportArchive(t, src)
+
+	dst := openTestDB(t)
+	if _, err := dst.ImportJSON(bytes.NewReader(archive)); err != nil {
+		t.Fatalf("first ImportJSON: %v", err)
+	}
+	// The local copy of m2 has since changed
+	if _, err := dst.db.Exec("UPDATE matches SET team_score = 7 WHERE match_id = 'm2'"); err != nil {
+		t.Fatal(err)
+	}
+	before := tableCounts(t, dst)
+
+	summary, err := dst.ImportJSON(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("second ImportJSON: %v", err)
+	}
+	if summary.MatchesAdded != 0 || summary.MatchesSkipped != 1 || summary.MatchesConflicting != 1 {
+		t.Errorf("added %d, skipped %d, conflicting %d; want 0, 1, 1",
+			summary.MatchesAdded, summary.MatchesSkipped, summary.MatchesConflicting)
+	}
+	if !reflect.DeepEqual(summary.Conflicts, []string{"m2"}) {
+		t.Errorf("conflicts = %v, want [m2]", summary.Conflicts)
+	}
+	if after := tableCounts(t, dst); !reflect.DeepEqual(after, before) {
+		t.Errorf("row counts changed from %v to %v", before, after)
+	}
+
+	var teamScore int
+	if err := dst.db.QueryRow("SELECT team_score FROM matches WHERE match_id = 'm2'").Scan(&teamScore); err != nil {
+		t.Fatal(err)
+	}
+	if teamScore != 7 {
+		t.Errorf("local copy of m2 has team score %d, want it kept at 7", teamScore)
+	}
+}
+
+func TestArchiveImportAfterWatcherImport(t *testing.T) {
+	src := openTestDB(t)
+	seedMatch(t, src, "m1", 2)
+	seedMatch(t, src, "m2", 2)
+	archived := decodeArchiveMatches(t, exportArchive(t, src))
+
+	// The watcher imported both matches after ImportJSON checked for them,
+	// m2 with a round more than the archive has
+	dst := openTestDB(t)
+	seedMatch(t, dst, "m1", 2)
+	seedMatch(t, dst, "m2", 3)
+	before := tableCounts(t, dst)
+
+	summary := &ArchiveImportSummary{}
+	for i := range archived {
+		if err := dst.addArchiveMatch(&archived[i], summary); err != nil {
+			t.Fatalf("addArchiveMatch(%s): %v", archived[i].MatchID, err)
+		}
+	}
+	if summary.MatchesAdded != 0 || summary.MatchesSkipped != 1 || summary.MatchesConflicting != 1 {
+		t.Errorf("added %d, skipped %d, conflicting %d; want 0, 1, 1",
+			summary.MatchesAdded, summary.MatchesSkipped, summary.MatchesConflicting)
+	}
+	if after := tableCounts(t, dst); !reflect.DeepEqual(after, before) {
+		t.Errorf("row counts changed from %v to %v", before, after)
+	}
+}
+
+func TestArchiveImportUpgradesV1(t *testing.T) {
+	db := openTestDB(t)
+	summary, err := db.ImportJSON(bytes.NewReader(v1Archive(t)))
+	if err != nil {
+		t.Fatalf("ImportJSON: %v", err)
+	}
+	if summary.MatchesAdded != 2 || summary.RoundsAdded != 3 || summary.RawRoundsAdded != 0 {
+		t.Errorf("summary = %+v, want 2 matches and 3 rounds without raw data", *summary)
+	}
+
+	results := teamPerspectiveResults(t, db)
+	if got := results["decided"]; got.RecordingTeamIndex != 1 || got.Won || got.TeamScore != 2 || got.OpponentScore != 3 {
+		t.Errorf("decided match = %+v, want it from team 1's side: lost 2-3", got)
+	}
+}
+
+func TestArchiveImportRejectsUnknownArchives(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive string
+	}{
+		{"not json", `matches`},
+		{"missing format", `{"version":1,"matches":[]}`},
+		{"other format", `{"format":"something-else","version":1,"matches":[]}`},
+		{"newer version", fmt.Sprintf(`{"format":%q,"version":%d,"matches":[]}`, ArchiveFormat, ArchiveVersion+1)},
+		{"version zero", fmt.Sprintf(`{"format":%q,"version":0,"matches":[]}`, ArchiveFormat)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			if _, err := db.ImportJSON(bytes.NewReader([]byte(tt.archive))); err == nil {
+				t.Error("ImportJSON succeeded, want an error")
+			}
+			if n, err := db.GetMatchCount(); err != nil || n != 0 {
+				t.Errorf("GetMatchCount = %d, %v; want 0", n, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"r6-replay-recorder/models"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ArchiveImportSummary describes what ImportJSON merged into the database
+type ArchiveImportSummary struct {
+	MatchesAdded       int      `json:"matchesAdded"`
+	MatchesSkipped     int      `json:"matchesSkipped"`
+	MatchesConflicting int      `json:"matchesConflicting"`
+	RoundsAdded        int      `json:"roundsAdded"`
+	PlayersAdded       int      `json:"playersAdded"`
+	EventsAdded        int      `json:"eventsAdded"`
+	StatsAdded         int      `json:"statsAdded"`
+	Conflicts          []string `json:"conflicts,omitempty"`
+
+	// RawRoundsAdded counts added rounds that came with their decoded replay.
+	// The rest can only be re-analyzed from their .rec files.
+	RawRoundsAdded int `json:"rawRoundsAdded"`
+	AliasesMerged  int `json:"aliasesMerged"`
+}
+
+// ImportJSON merges a JSON archive written by ExportJSON into the database.
+// Matches are de-duplicated by match_id: an identical match already in the
+// library is skipped, one that differs is left untouched and reported as a
+// conflict. Every added match is written in its own transaction, along with
+// the raw rounds it was archived with. Player aliases are merged last.
+func (d *Database) ImportJSON(r io.Reader) (*ArchiveImportSummary, error) {
+	dec := json.NewDecoder(r)
+	summary := &ArchiveImportSummary{}
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return summary, fmt.Errorf("not a SiegeScope archive: %w", err)
+	}
+
+	var header ArchiveHeader
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return summary, err
+		}
+		key, _ := tok.(string)
+
+		switch key {
+		case "format":
+			if err := dec.Decode(&header.Format); err != nil {
+				return summary, err
+			}
+		case "version":
+			if err := dec.Decode(&header.Version); err != nil {
+				return summary, err
+			}
+		case "exportedAt":
+			if err := dec.Decode(&header.ExportedAt); err != nil {
+				return summary, err
+			}
+		case "matches":
+			if err := checkArchiveHeader(header); err != nil {
+				return summary, err
+			}
+			if err := d.importArchiveMatches(dec, header.Version, summary); err != nil {
+				return summary, err
+			}
+		case "aliases":
+			if err := checkArchiveHeader(header); err != nil {
+				return summary, err
+			}
+			var aliases []models.PlayerAlias
+			if err := dec.Decode(&aliases); err != nil {
+				return summary, fmt.Errorf("failed to decode player aliases in archive: %w", err)
+			}
+			if err := d.mergeArchiveAliases(aliases, summary); err != nil {
+				return summary, err
+			}
+		default:
+			// Skip fields added by newer exporters
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return summary, err
+			}
+		}
+	}
+
+	if err := checkArchiveHeader(header); err != nil {
+		return summary, err
+	}
+
+	return summary, expectDelim(dec, '}')
+}
+
+func checkArchiveHeader(header ArchiveHeader) error {
+	if header.Format != ArchiveFormat {
+		return errors.New("not a SiegeScope archive: missing or unknown format")
+	}
+	if header.Version < 1 || header.Version > ArchiveVersion {
+		return fmt.Errorf("archive version %d is not supported (this build reads up to version %d)", header.Version, ArchiveVersion)
+	}
+	return nil
+}
+
+func (d *Database) importArchiveMatches(dec *json.Decoder, version int, summary *ArchiveImportSummary) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		var archived ArchiveMatch
+		if err := dec.Decode(&archived); err != nil {
+			return fmt.Errorf("failed to decode match %d in archive: %w",
+				summary.MatchesAdded+summary.MatchesSkipped+summary.MatchesConflicting+1, err)
+		}
+		if version < 2 {
+			upgradeTeamPerspective(&archived)
+		}
+
+		if err := d.mergeArchiveMatch(&archived, summary); err != nil {
+			return fmt.Errorf("failed to import match %s: %w", archived.MatchID, err)
+		}
+	}
+
+	return expectDelim(dec, ']')
+}
+
+// mergeArchiveMatch adds a single archived match unless its match_id is already known
+func (d *Database) mergeArchiveMatch(archived *ArchiveMatch, summary *ArchiveImportSummary) error {
+	exists, err := d.MatchExists(archived.MatchID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return d.mergeExistingMatch(archived, summary)
+	}
+	return d.addArchiveMatch(archived, summary)
+}
+
+// mergeExistingMatch counts an archived match the library already has as
+// skipped, or as a conflict if the local copy differs
+func (d *Database) mergeExistingMatch(archived *ArchiveMatch, summary *ArchiveImportSummary) error {
+	same, err := d.sameAsArchived(archived)
+	if err != nil {
+		return err
+	}
+	if same {
+		summary.MatchesSkipped++
+	} else {
+		summary.MatchesConflicting++
+		summary.Conflicts = append(summary.Conflicts, archived.MatchID)
+	}
+	return nil
+}
+
+// addArchiveMatch writes an archived match in one transaction. A match
+// imported by the watcher since mergeArchiveMatch checked for it is merged
+// as an existing one instead.
+func (d *Database) addArchiveMatch(archived *ArchiveMatch, summary *ArchiveImportSummary) error {
+	w, err := d.BeginMatch()
+	if err != nil {
+		return err
+	}
+	defer w.Rollback()
+
+	var rounds, players, events, stats, raw int
+
+	matchDBID, err := w.InsertMatch(&archived.Match)
+	if isUniqueViolation(err) {
+		w.Rollback()
+		return d.mergeExistingMatch(archived, summary)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, ar := range archived.Rounds {
+		round := ar.Round
+		round.MatchID = matchDBID
+		roundDBID, err := w.InsertRound(&round)
+		if err != nil {
+			return err
+		}
+		rounds++
+
+		for _, player := range ar.Players {
+			player.RoundID, player.MatchID = roundDBID, matchDBID
+			if err := w.InsertPlayer(&player); err != nil {
+				return err
+			}
+			players++
+		}
+
+		for _, event := range ar.Events {
+			event.RoundID, event.MatchID = roundDBID, matchDBID
+			if err := w.InsertEvent(&event); err != nil {
+				return err
+			}
+			events++
+		}
+
+		for _, s := range ar.PlayerRoundStats {
+			s.RoundID, s.MatchID = roundDBID, matchDBID
+			if err := w.InsertPlayerRoundStats(&s); err != nil {
+				return err
+			}
+			stats++
+		}
+
+		if len(ar.Raw) > 0 {
+			if err := w.InsertRoundRaw(roundDBID, matchDBID, ar.Raw); err != nil {
+				return err
+			}
+			raw++
+		}
+	}
+
+	if err := w.Commit(); err != nil {
+		return err
+	}
+
+	summary.MatchesAdded++
+	summary.RoundsAdded += rounds
+	summary.PlayersAdded += players
+	summary.EventsAdded += events
+	summary.StatsAdded += stats
+	summary.RawRoundsAdded += raw
+	return nil
+}
+
+// mergeArchiveAliases records every archived alias, widening the first and
+// last seen dates of aliases the library already knows
+func (d *Database) mergeArchiveAliases(aliases []models.PlayerAlias, summary *ArchiveImportSummary) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	merged := 0
+	for _, a := range aliases {
+		if a.ProfileID == "" {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO player_aliases (profile_id, username, first_seen, last_seen)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (profile_id, username) DO UPDATE SET
+				first_seen = MIN(first_seen, excluded.first_seen),
+				last_seen = MAX(last_seen, excluded.last_seen)`,
+			a.ProfileID, a.Username, a.FirstSeen, a.LastSeen,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to import alias %s of %s: %w", a.Username, a.ProfileID, err)
+		}
+		merged++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	summary.AliasesMerged += merged
+	return nil
+}
+
+// upgradeTeamPerspective converts a version 1 archived match, whose results
+// always described team 0, to the recording player's side. This mirrors the
+// recording team schema migration, so every round result flips and only
+// drawn matches keep theirs.
+func upgradeTeamPerspective(archived *ArchiveMatch) {
+	archived.RecordingTeamIndex = 0
+find:
+	for _, round := range archived.Rounds {
+		for _, player := range round.Players {
+			if archived.ProfileID != "" && player.ProfileID == archived.ProfileID {
+				if player.TeamIndex == 1 {
+					archived.RecordingTeamIndex = 1
+				}
+				break find
+			}
+		}
+	}
+	if archived.RecordingTeamIndex == 0 {
+		return
+	}
+
+	if archived.TeamScore != archived.OpponentScore {
+		archived.Won = !archived.Won
+	}
+	archived.TeamScore, archived.OpponentScore = archived.OpponentScore, archived.TeamScore
+	for i := range archived.Rounds {
+		r := &archived.Rounds[i].Round
+		switch r.TeamRole {
+		case "Attack":
+			r.TeamRole = "Defense"
+		case "Defense":
+			r.TeamRole = "Attack"
+		}
+		r.Won = !r.Won
+		r.TeamScore, r.OpponentScore = r.OpponentScore, r.TeamScore
+	}
+}
+
+// isUniqueViolation reports whether err is a UNIQUE constraint failure
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// sameAsArchived reports whether the local copy of a match agrees with the archived one
+func (d *Database) sameAsArchived(archived *ArchiveMatch) (bool, error) {
+	var mapName string
+	var teamScore, opponentScore, roundCount int
+	err := d.db.QueryRow(`
+		SELECT m.map, m.team_score, m.opponent_score,
+		       (SELECT COUNT(*) FROM rounds r WHERE r.match_id = m.id)
+		FROM matches m WHERE m.match_id = ?
+	`, archived.MatchID).Scan(&mapName, &teamScore, &opponentScore, &roundCount)
+	if err != nil {
+		return false, err
+	}
+
+	return mapName == archived.Map &&
+		teamScore == archived.TeamScore &&
+		opponentScore == archived.OpponentScore &&
+		roundCount == len(archived.Rounds), nil
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q in archive, got %v", want, tok)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"r6-replay-recorder/models"
+)
+
+// teamPerspectiveRound is a round as stored before the recording team was
+// tracked, from team 0's side
+type teamPerspectiveRound struct {
+	role                     string
+	won                      bool
+	winCondition             string
+	teamScore, opponentScore int
+}
+
+type teamPerspectiveMatch struct {
+	matchID                  string
+	teamScore, opponentScore int
+	won                      bool
+	rounds                   []teamPerspectiveRound
+}
+
+// teamPerspectiveMatches are recorded by p1 on team 1 against p2 on team 0:
+// one decided match and one drawn match. Rounds without a win condition
+// still had a winner, as in replays from Y9S4 on.
+var teamPerspectiveMatches = []teamPerspectiveMatch{
+	{
+		matchID: "decided", teamScore: 3, opponentScore: 2, won: true,
+		rounds: []teamPerspectiveRound{
+			{role: "Attack", won: true, winCondition: "KilledOpponents", teamScore: 1, opponentScore: 0},
+			{role: "Defense", won: false, winCondition: "", teamScore: 1, opponentScore: 1},
+		},
+	},
+	{
+		matchID: "drawn", teamScore: 2, opponentScore: 2, won: false,
+		rounds: []teamPerspectiveRound{
+			{role: "Defense", won: true, winCondition: "", teamScore: 2, opponentScore: 2},
+		},
+	},
+}
+
+var teamPerspectivePlayers = []models.Player{
+	{ProfileID: "p1", Username: "recorder", TeamIndex: 1, Operator: "Ash"},
+	{ProfileID: "p2", Username: "opponent", TeamIndex: 0, Operator: "Mute"},
+}
+
+// openV4Library writes teamPerspectiveMatches to a library at schema version
+// 4, the last one that stored results from team 0's side, and then opens it
+// so the remaining migrations run
+func openV4Library(t *testing.T) *Database {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "replays.db")
+
+	raw, err := sql.Open("sqlite3", path+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := &Database{db: raw, path: path}
+	for _, m := range migrations {
+		if m.version > 4 {
+			break
+		}
+		if err := old.applyMigration(m); err != nil {
+			t.Fatalf("migration %d: %v", m.version, err)
+		}
+	}
+
+	for _, tm := range teamPerspectiveMatches {
+		res, err := raw.Exec(`
+			INSERT INTO matches (match_id, game_version, code_version, timestamp, match_type,
+				game_mode, map, recording_player, profile_id, team_score, opponent_score,
+				won, rounds_played, file_path)
+			VALUES (?, '', 0, ?, '', '', 'Bank', 'recorder', 'p1', ?, ?, ?, ?, '')`,
+			tm.matchID, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			tm.teamScore, tm.opponentScore, tm.won, len(tm.rounds))
+		if err != nil {
+			t.Fatal(err)
+		}
+		matchDBID, _ := res.LastInsertId()
+
+		for i, tr := range tm.rounds {
+			res, err := raw.Exec(`
+				INSERT INTO rounds (match_id, round_number, site, team_role, won,
+					win_condition, team_score, opponent_score)
+				VALUES (?, ?, '', ?, ?, ?, ?, ?)`,
+				matchDBID, i+1, tr.role, tr.won, tr.winCondition, tr.teamScore, tr.opponentScore)
+			if err != nil {
+				t.Fatal(err)
+			}
+			roundDBID, _ := res.LastInsertId()
+
+			for _, p := range teamPerspectivePlayers {
+				if _, err := raw.Exec(`
+					INSERT INTO players (round_id, match_id, profile_id, username, team_index, operator, spawn)
+					VALUES (?, ?, ?, ?, ?, ?, '')`,
+					roundDBID, matchDBID, p.ProfileID, p.Username, p.TeamIndex, p.Operator); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := raw.Exec(`
+					INSERT INTO player_round_stats (round_id, match_id, username, team_index, operator)
+					VALUES (?, ?, ?, ?, ?)`,
+					roundDBID, matchDBID, p.Username, p.TeamIndex, p.Operator); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+	raw.Close()
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// v1Archive encodes teamPerspectiveMatches as a version 1 archive
+func v1Archive(t *testing.T) []byte {
+	t.Helper()
+	var matches []ArchiveMatch
+	for _, tm := range teamPerspectiveMatches {
+		am := ArchiveMatch{Match: models.Match{
+			MatchID: tm.matchID, Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			Map: "Bank", RecordingPlayer: "recorder", ProfileID: "p1",
+			TeamScore: tm.teamScore, OpponentScore: tm.opponentScore, Won: tm.won,
+			RoundsPlayed: len(tm.rounds),
+		}}
+		for i, tr := range tm.rounds {
+			ar := ArchiveRound{
+				Round: models.Round{
+					RoundNumber: i + 1, TeamRole: tr.role, Won: tr.won, WinCondition: tr.winCondition,
+					TeamScore: tr.teamScore, OpponentScore: tr.opponentScore,
+				},
+				Players: teamPerspectivePlayers,
+				Events:  []models.MatchEvent{},
+			}
+			for _, p := range teamPerspectivePlayers {
+				ar.PlayerRoundStats = append(ar.PlayerRoundStats, models.PlayerRoundStats{
+					Username: p.Username, TeamIndex: p.TeamIndex, Operator: p.Operator,
+				})
+			}
+			am.Rounds = append(am.Rounds, ar)
+		}
+		matches = append(matches, am)
+	}
+
+	data, err := json.Marshal(struct {
+		ArchiveHeader
+		Matches []ArchiveMatch `json:"matches"`
+	}{ArchiveHeader{Format: ArchiveFormat, Version: 1}, matches})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// teamPerspectiveResult is what a library stores about a match's results
+type teamPerspectiveResult struct {
+	RecordingTeamIndex       int
+	Won                      bool
+	TeamScore, OpponentScore int
+	Rounds                   []teamPerspectiveRound
+}
+
+func teamPerspectiveResults(t *testing.T, db *Database) map[string]teamPerspectiveResult {
+	t.Helper()
+	matches, err := db.GetAllMatches()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(map[string]teamPerspectiveResult)
+	for _, m := range matches {
+		res := teamPerspectiveResult{
+			RecordingTeamIndex: m.RecordingTeamIndex,
+			Won:                m.Won,
+			TeamScore:          m.TeamScore,
+			OpponentScore:      m.OpponentScore,
+		}
+		rounds, err := db.GetRoundsByMatch(m.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range rounds {
+			res.Rounds = append(res.Rounds, teamPerspectiveRound{
+				role: r.TeamRole, won: r.Won, winCondition: r.WinCondition,
+				teamScore: r.TeamScore, opponentScore: r.OpponentScore,
+			})
+		}
+		results[m.MatchID] = res
+	}
+	return results
+}
+
+func TestTeamPerspectiveArchiveUpgradeMatchesMigration(t *testing.T) {
+	migrated := openV4Library(t)
+
+	imported := openTestDB(t)
+	if _, err := imported.ImportJSON(bytes.NewReader(v1Archive(t))); err != nil {
+		t.Fatalf("ImportJSON: %v", err)
+	}
+
+	got, want := teamPerspectiveResults(t, imported), teamPerspectiveResults(t, migrated)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("archive upgrade and migration disagree:\n archive:   %+v\n migration: %+v", got, want)
+	}
+
+	wantResults := map[string]teamPerspectiveResult{
+		"decided": {
+			RecordingTeamIndex: 1, Won: false, TeamScore: 2, OpponentScore: 3,
+			Rounds: []teamPerspectiveRound{
+				{role: "Defense", won: false, winCondition: "KilledOpponents", teamScore: 0, opponentScore: 1},
+				{role: "Attack", won: true, winCondition: "", teamScore: 1, opponentScore: 1},
+			},
+		},
+		"drawn": {
+			RecordingTeamIndex: 1, Won: false, TeamScore: 2, OpponentScore: 2,
+			Rounds: []teamPerspectiveRound{
+				{role: "Attack", won: false, winCondition: "", teamScore: 2, opponentScore: 2},
+			},
+		},
+	}
+	if !reflect.DeepEqual(want, wantResults) {
+		t.Errorf("migrated results = %+v, want %+v", want, wantResults)
+	}
+}
+
+func TestOpponentResultsWithoutWinCondition(t *testing.T) {
+	for name, db := range map[string]*Database{
+		"migration": openV4Library(t),
+		"archive":   openTestDB(t),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if name == "archive" {
+				if _, err := db.ImportJSON(bytes.NewReader(v1Archive(t))); err != nil {
+					t.Fatalf("ImportJSON: %v", err)
+				}
+			}
+
+			// The opponent won the decided match and two rounds, one of them
+			// without a win condition; the drawn match is lost for both
+			stats, err := db.GetPlayerStats("p2")
+			if err != nil || stats == nil {
+				t.Fatalf("GetPlayerStats = %v, %v", stats, err)
+			}
+			if stats.MatchesPlayed != 2 || stats.MatchesWon != 1 {
+				t.Errorf("opponent won %d of %d matches, want 1 of 2", stats.MatchesWon, stats.MatchesPlayed)
+			}
+
+			ops, err := db.GetOperatorStats(models.StatsFilter{Player: "opponent"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			won := make(map[string]int)
+			for _, op := range ops {
+				won[op.Operator] = op.RoundsWon
+			}
+			if want := map[string]int{"Mute": 2}; !reflect.DeepEqual(won, want) {
+				t.Errorf("rounds won by operator = %v, want %v", won, want)
+			}
+		})
+	}
+}
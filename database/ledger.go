@@ -0,0 +1,93 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Import ledger statuses
+const (
+	LedgerImported  = "imported"  // the folder's match was written to the library
+	LedgerDuplicate = "duplicate" // the folder's match was already in the library
+	LedgerFailed    = "failed"    // the folder could not be imported, see Error
+)
+
+// LedgerEntry is what the importer last saw in a replay folder and what it did with it
+type LedgerEntry struct {
+	FolderPath  string
+	FileCount   int
+	TotalSize   int64
+	LatestMtime time.Time
+	Status      string
+	MatchID     string
+	Error       string
+	UpdatedAt   time.Time
+}
+
+// SameFiles reports whether two entries describe identical .rec files
+func (e *LedgerEntry) SameFiles(other *LedgerEntry) bool {
+	return e.FileCount == other.FileCount &&
+		e.TotalSize == other.TotalSize &&
+		e.LatestMtime.Equal(other.LatestMtime)
+}
+
+// RecordImport adds or replaces the ledger entry for a folder
+func (d *Database) RecordImport(e *LedgerEntry) error {
+	_, err := d.db.Exec(`
+		INSERT OR REPLACE INTO import_ledger
+			(folder_path, file_count, total_size, latest_mtime, status, match_id, error, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.FolderPath, e.FileCount, e.TotalSize, e.LatestMtime.UnixNano(), e.Status,
+		nullString(e.MatchID), nullString(e.Error), time.Now())
+	return err
+}
+
+// GetLedgerEntry returns the ledger entry for a folder, or nil if it has never been imported
+func (d *Database) GetLedgerEntry(folderPath string) (*LedgerEntry, error) {
+	e, err := scanLedgerEntry(d.db.QueryRow(ledgerSelect+" WHERE folder_path = ?", folderPath))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return e, err
+}
+
+// GetImportLedger returns every ledger entry keyed by folder path
+func (d *Database) GetImportLedger() (map[string]*LedgerEntry, error) {
+	rows, err := d.db.Query(ledgerSelect)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ledger := make(map[string]*LedgerEntry)
+	for rows.Next() {
+		e, err := scanLedgerEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		ledger[e.FolderPath] = e
+	}
+	return ledger, rows.Err()
+}
+
+const ledgerSelect = `
+	SELECT folder_path, file_count, total_size, latest_mtime, status,
+	       COALESCE(match_id, ''), COALESCE(error, ''), updated_at
+	FROM import_ledger`
+
+func scanLedgerEntry(row rowScanner) (*LedgerEntry, error) {
+	var e LedgerEntry
+	var mtime int64
+	err := row.Scan(&e.FolderPath, &e.FileCount, &e.TotalSize, &mtime, &e.Status,
+		&e.MatchID, &e.Error, &e.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	e.LatestMtime = time.Unix(0, mtime)
+	return &e, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
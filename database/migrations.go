@@ -0,0 +1,652 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migration is a single numbered schema change. Migrations run in order, each
+// in its own transaction, and must never be edited once they have shipped -
+// add a new one instead.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations is the ordered history of the schema. The last entry's version is
+// the schema version this build writes.
+var migrations = []migration{
+	{1, "initial schema", migrateInitialSchema},
+	{2, "advanced player round stats", migrateAdvancedRoundStats},
+	{3, "watch polling setting", migrateWatchPolling},
+	{4, "import ledger", migrateImportLedger},
+	{5, "recording team index", migrateRecordingTeamIndex},
+	{6, "kost and survival time", migrateKOSTAndSurvival},
+	{7, "analysis version", migrateAnalysisVersion},
+	{8, "raw rounds", migrateRoundRaw},
+	{9, "player profiles", migratePlayerProfiles},
+	{10, "analysis windows", migrateAnalysisWindows},
+	{11, "rating", migrateRating},
+}
+
+// SchemaVersion is the newest schema this build knows how to read and write
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// ErrSchemaTooNew is returned when replays.db was written by a newer build
+type ErrSchemaTooNew struct {
+	Found     int
+	Supported int
+}
+
+func (e *ErrSchemaTooNew) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than this build supports (%d); please update SiegeScope", e.Found, e.Supported)
+}
+
+// migrate brings the database up to SchemaVersion, recording progress in PRAGMA user_version
+func (d *Database) migrate() error {
+	var current int
+	if err := d.db.QueryRow("PRAGMA user_version").Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	if current > SchemaVersion() {
+		return &ErrSchemaTooNew{Found: current, Supported: SchemaVersion()}
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := d.applyMigration(m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (d *Database) applyMigration(m migration) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+
+	// PRAGMA does not accept bound parameters
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", m.version)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// columnExists reports whether table already has the named column
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name, kind string
+			notNull    bool
+			dflt       sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &dflt, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// addColumns adds each missing column to table. Databases created before
+// versioned migrations may already have some of them.
+func addColumns(tx *sql.Tx, table string, columns [][2]string) error {
+	for _, col := range columns {
+		exists, err := columnExists(tx, table, col[0])
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, col[0], col[1])); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %w", table, col[0], err)
+		}
+	}
+	return nil
+}
+
+// migrateInitialSchema creates the original tables. It uses IF NOT EXISTS so
+// that libraries created before user_version was tracked adopt it unchanged.
+func migrateInitialSchema(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS matches (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		match_id TEXT UNIQUE NOT NULL,
+		game_version TEXT,
+		code_version INTEGER,
+		timestamp DATETIME,
+		match_type TEXT,
+		game_mode TEXT,
+		map TEXT,
+		recording_player TEXT,
+		profile_id TEXT,
+		team_score INTEGER,
+		opponent_score INTEGER,
+		won BOOLEAN,
+		rounds_played INTEGER,
+		imported_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		file_path TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS rounds (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		match_id INTEGER NOT NULL,
+		round_number INTEGER,
+		site TEXT,
+		team_role TEXT,
+		won BOOLEAN,
+		win_condition TEXT,
+		team_score INTEGER,
+		opponent_score INTEGER,
+		FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS players (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		round_id INTEGER NOT NULL,
+		match_id INTEGER NOT NULL,
+		profile_id TEXT,
+		username TEXT,
+		team_index INTEGER,
+		operator TEXT,
+		spawn TEXT,
+		FOREIGN KEY (round_id) REFERENCES rounds(id) ON DELETE CASCADE,
+		FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS match_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		round_id INTEGER NOT NULL,
+		match_id INTEGER NOT NULL,
+		event_type TEXT,
+		time TEXT,
+		time_in_seconds INTEGER,
+		username TEXT,
+		target TEXT,
+		headshot BOOLEAN,
+		message TEXT,
+		FOREIGN KEY (round_id) REFERENCES rounds(id) ON DELETE CASCADE,
+		FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS player_round_stats (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		round_id INTEGER NOT NULL,
+		match_id INTEGER NOT NULL,
+		username TEXT,
+		team_index INTEGER,
+		operator TEXT,
+		kills INTEGER DEFAULT 0,
+		died BOOLEAN DEFAULT 0,
+		assists INTEGER DEFAULT 0,
+		headshots INTEGER DEFAULT 0,
+		headshot_percentage REAL DEFAULT 0,
+		entry_kill BOOLEAN DEFAULT 0,
+		entry_death BOOLEAN DEFAULT 0,
+		defuser_plants INTEGER DEFAULT 0,
+		defuser_defuses INTEGER DEFAULT 0,
+		defuser_pickups INTEGER DEFAULT 0,
+		plant_denials INTEGER DEFAULT 0,
+		clutch_attempts INTEGER DEFAULT 0,
+		clutch_wins INTEGER DEFAULT 0,
+		clutch_1v1 BOOLEAN DEFAULT 0,
+		clutch_1v2 BOOLEAN DEFAULT 0,
+		clutch_1v3 BOOLEAN DEFAULT 0,
+		clutch_1v4 BOOLEAN DEFAULT 0,
+		clutch_1v5 BOOLEAN DEFAULT 0,
+		double_kills INTEGER DEFAULT 0,
+		triple_kills INTEGER DEFAULT 0,
+		quad_kills INTEGER DEFAULT 0,
+		ace BOOLEAN DEFAULT 0,
+		trade_kills INTEGER DEFAULT 0,
+		trade_deaths INTEGER DEFAULT 0,
+		survival_time REAL DEFAULT 0,
+		survived BOOLEAN DEFAULT 0,
+		FOREIGN KEY (round_id) REFERENCES rounds(id) ON DELETE CASCADE,
+		FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS settings (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		replay_folder TEXT,
+		auto_import BOOLEAN DEFAULT 0,
+		theme TEXT DEFAULT 'dark',
+		start_minimized BOOLEAN DEFAULT 0,
+		start_with_system BOOLEAN DEFAULT 0,
+		api_key TEXT
+	);
+
+	-- Insert default settings if not exists
+	INSERT OR IGNORE INTO settings (id, theme) VALUES (1, 'dark');
+
+	-- Create indexes for performance
+	CREATE INDEX IF NOT EXISTS idx_matches_timestamp ON matches(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_matches_map ON matches(map);
+	CREATE INDEX IF NOT EXISTS idx_matches_match_type ON matches(match_type);
+	CREATE INDEX IF NOT EXISTS idx_rounds_match_id ON rounds(match_id);
+	CREATE INDEX IF NOT EXISTS idx_players_match_id ON players(match_id);
+	CREATE INDEX IF NOT EXISTS idx_players_username ON players(username);
+	CREATE INDEX IF NOT EXISTS idx_match_events_round_id ON match_events(round_id);
+	CREATE INDEX IF NOT EXISTS idx_player_round_stats_round_id ON player_round_stats(round_id);
+	CREATE INDEX IF NOT EXISTS idx_player_round_stats_match_id ON player_round_stats(match_id);
+	`)
+	return err
+}
+
+// migrateAdvancedRoundStats adds the defuser, clutch, multi-kill, trade and
+// survival columns to libraries created before they existed
+func migrateAdvancedRoundStats(tx *sql.Tx) error {
+	return addColumns(tx, "player_round_stats", [][2]string{
+		{"defuser_plants", "INTEGER DEFAULT 0"},
+		{"defuser_defuses", "INTEGER DEFAULT 0"},
+		{"defuser_pickups", "INTEGER DEFAULT 0"},
+		{"plant_denials", "INTEGER DEFAULT 0"},
+		{"clutch_attempts", "INTEGER DEFAULT 0"},
+		{"clutch_wins", "INTEGER DEFAULT 0"},
+		{"clutch_1v1", "BOOLEAN DEFAULT 0"},
+		{"clutch_1v2", "BOOLEAN DEFAULT 0"},
+		{"clutch_1v3", "BOOLEAN DEFAULT 0"},
+		{"clutch_1v4", "BOOLEAN DEFAULT 0"},
+		{"clutch_1v5", "BOOLEAN DEFAULT 0"},
+		{"double_kills", "INTEGER DEFAULT 0"},
+		{"triple_kills", "INTEGER DEFAULT 0"},
+		{"quad_kills", "INTEGER DEFAULT 0"},
+		{"ace", "BOOLEAN DEFAULT 0"},
+		{"trade_kills", "INTEGER DEFAULT 0"},
+		{"trade_deaths", "INTEGER DEFAULT 0"},
+		{"survival_time", "REAL DEFAULT 0"},
+		{"survived", "BOOLEAN DEFAULT 0"},
+	})
+}
+
+// migrateWatchPolling lets the folder watcher be forced back to polling for
+// replay folders on network drives, which don't deliver filesystem events
+func migrateWatchPolling(tx *sql.Tx) error {
+	return addColumns(tx, "settings", [][2]string{
+		{"watch_polling", "BOOLEAN DEFAULT 0"},
+	})
+}
+
+// migrateImportLedger records every replay folder the importer has looked at,
+// so unchanged folders can be skipped without decoding them
+func migrateImportLedger(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS import_ledger (
+		folder_path TEXT PRIMARY KEY,
+		file_count INTEGER NOT NULL,
+		total_size INTEGER NOT NULL,
+		latest_mtime INTEGER NOT NULL, -- unix nanoseconds
+		status TEXT NOT NULL,
+		match_id TEXT,
+		error TEXT,
+		updated_at DATETIME
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_import_ledger_match_id ON import_ledger(match_id);
+	`)
+	return err
+}
+
+// migrateRecordingTeamIndex records which team the recording player was on.
+// Older imports assumed team 0, so for matches where the recording player
+// was on team 1 the stored scores, results and round roles are flipped to
+// their side. Every round had a winner, even when the replay leaves its win
+// condition empty, so round results always flip; only a drawn match stays
+// lost for both teams.
+func migrateRecordingTeamIndex(tx *sql.Tx) error {
+	if err := addColumns(tx, "matches", [][2]string{
+		{"recording_team_index", "INTEGER DEFAULT 0"},
+	}); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`
+	UPDATE matches SET recording_team_index = COALESCE((
+		SELECT p.team_index FROM players p
+		WHERE p.match_id = matches.id AND p.profile_id = matches.profile_id AND p.profile_id != ''
+		LIMIT 1
+	), 0);
+
+	UPDATE rounds SET
+		team_role = CASE team_role WHEN 'Attack' THEN 'Defense' WHEN 'Defense' THEN 'Attack' ELSE team_role END,
+		won = NOT won,
+		team_score = opponent_score,
+		opponent_score = team_score
+	WHERE match_id IN (SELECT id FROM matches WHERE recording_team_index = 1);
+
+	UPDATE matches SET
+		won = CASE WHEN team_score != opponent_score THEN NOT won ELSE won END,
+		team_score = opponent_score,
+		opponent_score = team_score
+	WHERE recording_team_index = 1;
+	`)
+	return err
+}
+
+// migrateKOSTAndSurvival adds KOST and replaces the placeholder survival
+// times, which were the event timer reading rather than time alive, with
+// values recomputed from each round's stored feed
+func migrateKOSTAndSurvival(tx *sql.Tx) error {
+	if err := addColumns(tx, "player_round_stats", [][2]string{
+		{"kost", "BOOLEAN DEFAULT 0"},
+	}); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT id, COALESCE(win_condition, '') FROM rounds")
+	if err != nil {
+		return err
+	}
+	winConditions := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var winCondition string
+		if err := rows.Scan(&id, &winCondition); err != nil {
+			rows.Close()
+			return err
+		}
+		winConditions[id] = winCondition
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for roundID, winCondition := range winConditions {
+		if err := recomputeRoundSurvival(tx, roundID, winCondition); err != nil {
+			return fmt.Errorf("round %d: %w", roundID, err)
+		}
+	}
+	return nil
+}
+
+// recomputeRoundSurvival rewrites survival_time, survived and kost for every
+// player in a stored round. The computation is frozen as it was when this
+// migration shipped, so it doesn't follow later analysis changes; rows it
+// writes are still re-analyzed once migration 7 tags them as outdated.
+func recomputeRoundSurvival(tx *sql.Tx, roundID int64, winCondition string) error {
+	rows, err := tx.Query(`
+		SELECT COALESCE(event_type, ''), COALESCE(time_in_seconds, 0), COALESCE(username, ''), COALESCE(target, '')
+		FROM match_events WHERE round_id = ? ORDER BY id
+	`, roundID)
+	if err != nil {
+		return err
+	}
+	var events []v6Event
+	for rows.Next() {
+		var e v6Event
+		if err := rows.Scan(&e.eventType, &e.time, &e.username, &e.target); err != nil {
+			rows.Close()
+			return err
+		}
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	type playerRound struct {
+		id        int64
+		kills     int
+		objective bool
+	}
+	rows, err = tx.Query(`
+		SELECT id, username, team_index, kills, defuser_plants > 0 OR defuser_defuses > 0
+		FROM player_round_stats WHERE round_id = ?
+	`, roundID)
+	if err != nil {
+		return err
+	}
+	teams := make(map[string]int)
+	players := make(map[string]playerRound)
+	for rows.Next() {
+		var username string
+		var team int
+		var p playerRound
+		if err := rows.Scan(&p.id, &username, &team, &p.kills, &p.objective); err != nil {
+			rows.Close()
+			return err
+		}
+		teams[username] = team
+		players[username] = p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for username, s := range v6RoundSurvival(events, teams, winCondition) {
+		p := players[username]
+		kost := p.kills > 0 || p.objective || s.survived || s.traded
+		_, err := tx.Exec("UPDATE player_round_stats SET survival_time = ?, survived = ?, kost = ? WHERE id = ?",
+			s.time, s.survived, kost, p.id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// v6Event is a stored feed entry as read by migration 6
+type v6Event struct {
+	eventType string
+	time      float64 // countdown timer reading
+	username  string
+	target    string
+}
+
+// v6Survival is how a player's round ended, as computed by migration 6
+type v6Survival struct {
+	time     float64
+	survived bool
+	traded   bool
+}
+
+// v6RoundSurvival is the survival and trade analysis migration 6 shipped
+// with: a 180 second action phase, a 45 second defuser timer after a plant
+// and a 3 second trade window. Keep it as is.
+func v6RoundSurvival(events []v6Event, teams map[string]int, winCondition string) map[string]*v6Survival {
+	const (
+		actionPhase = 180.0
+		defuser     = 45.0
+		tradeWindow = 3.0
+	)
+	timed := map[string]bool{
+		"Kill":                   true,
+		"Death":                  true,
+		"DefuserPlantStart":      true,
+		"DefuserPlantComplete":   true,
+		"DefuserDisableStart":    true,
+		"DefuserDisableComplete": true,
+	}
+	type death struct {
+		victim string
+		killer string
+		time   float64
+	}
+
+	var planted bool
+	var plantedAt, last float64
+	diedAt := make(map[string]float64)
+	var deaths []death
+
+	for _, e := range events {
+		if timed[e.eventType] {
+			var elapsed float64
+			if planted {
+				elapsed = plantedAt + defuser - e.time
+			} else {
+				elapsed = actionPhase - e.time
+			}
+			if elapsed < last {
+				elapsed = last
+			}
+			last = elapsed
+			if e.eventType == "DefuserPlantComplete" && !planted {
+				planted = true
+				plantedAt = elapsed
+			}
+		}
+
+		var gone, killer string
+		switch e.eventType {
+		case "Kill":
+			gone, killer = e.target, e.username
+		case "Death", "PlayerLeave":
+			gone = e.username
+		default:
+			continue
+		}
+		if _, dead := diedAt[gone]; dead {
+			continue
+		}
+		diedAt[gone] = last
+		deaths = append(deaths, death{victim: gone, killer: killer, time: last})
+	}
+
+	length := last
+	switch {
+	case winCondition == "Time" && !planted:
+		length = actionPhase
+	case winCondition == "DefusedBomb" && planted:
+		length = plantedAt + defuser
+	}
+
+	result := make(map[string]*v6Survival, len(teams))
+	for username := range teams {
+		s := &v6Survival{time: length, survived: true}
+		if t, dead := diedAt[username]; dead {
+			s.time, s.survived = t, false
+		}
+		result[username] = s
+	}
+
+	for i, d := range deaths {
+		if d.killer == "" || result[d.victim] == nil {
+			continue
+		}
+		for _, later := range deaths[i+1:] {
+			if later.time-d.time > tradeWindow {
+				break
+			}
+			if later.victim != d.killer {
+				continue
+			}
+			if team, ok := teams[later.killer]; ok && team == teams[d.victim] {
+				result[d.victim].traded = true
+			}
+			break
+		}
+	}
+
+	return result
+}
+
+// migrateAnalysisVersion tags player round stats with the analysis version
+// that produced them. Existing rows are left at 0 so they show up as
+// outdated and can be re-analyzed from their replays.
+func migrateAnalysisVersion(tx *sql.Tx) error {
+	return addColumns(tx, "player_round_stats", [][2]string{
+		{"analysis_version", "INTEGER DEFAULT 0"},
+	})
+}
+
+// migrateRoundRaw stores each round's full decoded replay, so rounds can be
+// re-analyzed after their .rec files are deleted
+func migrateRoundRaw(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS round_raw (
+		round_id INTEGER PRIMARY KEY,
+		match_id INTEGER NOT NULL,
+		data BLOB NOT NULL, -- gzip compressed JSON
+		FOREIGN KEY (round_id) REFERENCES rounds(id) ON DELETE CASCADE,
+		FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_round_raw_match_id ON round_raw(match_id);
+	`)
+	return err
+}
+
+// migratePlayerProfiles ties player round stats to Ubisoft profile IDs, so
+// players who change their username are still counted as one person, and
+// records every username seen per profile
+func migratePlayerProfiles(tx *sql.Tx) error {
+	if err := addColumns(tx, "player_round_stats", [][2]string{
+		{"profile_id", "TEXT DEFAULT ''"},
+	}); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`
+	UPDATE player_round_stats SET profile_id = COALESCE((
+		SELECT p.profile_id FROM players p
+		WHERE p.round_id = player_round_stats.round_id AND p.username = player_round_stats.username
+		LIMIT 1
+	), '');
+
+	CREATE INDEX IF NOT EXISTS idx_player_round_stats_profile_id ON player_round_stats(profile_id);
+
+	CREATE TABLE IF NOT EXISTS player_aliases (
+		profile_id TEXT NOT NULL,
+		username TEXT NOT NULL,
+		first_seen DATETIME,
+		last_seen DATETIME,
+		PRIMARY KEY (profile_id, username)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_player_aliases_username ON player_aliases(username);
+
+	INSERT OR IGNORE INTO player_aliases (profile_id, username, first_seen, last_seen)
+	SELECT p.profile_id, p.username, MIN(m.timestamp), MAX(m.timestamp)
+	FROM players p JOIN matches m ON m.id = p.match_id
+	WHERE p.profile_id != ''
+	GROUP BY p.profile_id, p.username;
+	`)
+	return err
+}
+
+// migrateAnalysisWindows makes the trade and multi-kill windows settings, so
+// stats can be computed with a team's own definitions
+func migrateAnalysisWindows(tx *sql.Tx) error {
+	return addColumns(tx, "settings", [][2]string{
+		{"trade_window", "REAL DEFAULT 3"},
+		{"multi_kill_window", "REAL DEFAULT 10"},
+	})
+}
+
+// migrateRating adds the SiegeScope Rating. Existing rows keep a rating of
+// 0 until they are re-analyzed, which the analysis version bump that came
+// with it flags them for.
+func migrateRating(tx *sql.Tx) error {
+	return addColumns(tx, "player_round_stats", [][2]string{
+		{"rating", "REAL DEFAULT 0"},
+	})
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"fmt"
+
+	"r6-replay-recorder/models"
+)
+
+// Opening duel groupings accepted by GetOpeningDuelStats
+const (
+	OpeningDuelsByPlayer   = "player"
+	OpeningDuelsByOperator = "operator"
+	OpeningDuelsBySide     = "side"
+	OpeningDuelsByMap      = "map"
+	OpeningDuelsBySite     = "site"
+)
+
+// openingDuelGroup is the columns selected into the identity fields of
+// OpeningDuelStats and the GROUP BY clause for one grouping
+type openingDuelGroup struct {
+	columns string // profile_id, username, operator, side, map, site
+	groupBy string
+	orderBy string
+}
+
+var openingDuelGroups = map[string]openingDuelGroup{
+	OpeningDuelsByPlayer: {
+		columns: `s.profile_id, ` + currentNameExpr("s.profile_id", "s.username") + `, '', '', '', ''`,
+		groupBy: playerKeyExpr,
+	},
+	OpeningDuelsByOperator: {
+		columns: `'', '', s.operator, '', '', ''`,
+		groupBy: "s.operator",
+		orderBy: "s.operator",
+	},
+	OpeningDuelsBySide: {
+		columns: `'', '', '', ` + playerRoleExpr + `, '', ''`,
+		groupBy: playerRoleExpr,
+		orderBy: playerRoleExpr,
+	},
+	OpeningDuelsByMap: {
+		columns: `'', '', '', '', m.map, ''`,
+		groupBy: "m.map",
+		orderBy: "m.map",
+	},
+	OpeningDuelsBySite: {
+		columns: `'', '', '', '', m.map, r.site`,
+		groupBy: "m.map, r.site",
+		orderBy: "m.map, r.site",
+	},
+}
+
+// GetOpeningDuelStats returns opening duels won and lost, and the round win
+// rate after each, grouped by one of the OpeningDuelsBy groupings, most
+// duels first. Duels are those of filter.Player, or of the whole recording
+// team when no player is set.
+func (d *Database) GetOpeningDuelStats(by string, filter models.StatsFilter) ([]models.OpeningDuelStats, error) {
+	group, ok := openingDuelGroups[by]
+	if !ok {
+		return nil, fmt.Errorf("unknown opening duel grouping %q", by)
+	}
+
+	var where string
+	var args []interface{}
+	if filterSet(filter.Player) {
+		where, args = statsFilterWhere(filter)
+	} else {
+		where, args = roundFilterWhere(filter)
+		where = " WHERE s.team_index = m.recording_team_index" + where
+	}
+	where += " AND (s.entry_kill OR s.entry_death)"
+	if by == OpeningDuelsBySite {
+		where += " AND r.site != ''"
+	}
+
+	orderBy := "duels DESC"
+	if group.orderBy != "" {
+		orderBy += ", " + group.orderBy
+	}
+
+	rows, err := d.db.Query(`
+		SELECT
+			`+group.columns+`,
+			COUNT(*) as duels,
+			SUM(CASE WHEN s.entry_kill THEN 1 ELSE 0 END) as won,
+			SUM(CASE WHEN s.entry_death THEN 1 ELSE 0 END) as lost,
+			SUM(CASE WHEN s.entry_kill AND `+playerWonExpr+` THEN 1 ELSE 0 END) as rounds_won_after_win,
+			SUM(CASE WHEN s.entry_death AND `+playerWonExpr+` THEN 1 ELSE 0 END) as rounds_won_after_loss
+		`+filteredRoundStatsFrom+where+`
+		GROUP BY `+group.groupBy+`
+		ORDER BY `+orderBy+`
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []models.OpeningDuelStats
+	for rows.Next() {
+		var s models.OpeningDuelStats
+		err := rows.Scan(&s.ProfileID, &s.Username, &s.Operator, &s.Side, &s.Map, &s.Site,
+			&s.Duels, &s.Won, &s.Lost, &s.RoundsWonAfterWin, &s.RoundsWonAfterLoss)
+		if err != nil {
+			return nil, err
+		}
+		s.Differential = s.Won - s.Lost
+		if s.Duels > 0 {
+			s.WinRate = float64(s.Won) / float64(s.Duels) * 100
+		}
+		if s.Won > 0 {
+			s.RoundWinRateAfterWin = float64(s.RoundsWonAfterWin) / float64(s.Won) * 100
+		}
+		if s.Lost > 0 {
+			s.RoundWinRateAfterLoss = float64(s.RoundsWonAfterLoss) / float64(s.Lost) * 100
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,222 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"r6-replay-recorder/models"
+)
+
+// playerMatchWonExpr is whether the team of player_round_stats row s won
+// match m. A drawn match is lost for both teams.
+const playerMatchWonExpr = `CASE WHEN s.team_index = m.recording_team_index THEN m.won
+	WHEN m.team_score != m.opponent_score THEN NOT m.won
+	ELSE 0 END`
+
+// playerKeyExpr identifies the player of player_round_stats row s: their
+// profile ID, or their username for rounds recorded without one
+const playerKeyExpr = `COALESCE(NULLIF(s.profile_id, ''), s.username)`
+
+// currentNameExpr is the username most recently seen with profileExpr,
+// falling back to fallbackExpr when the profile has no recorded aliases
+func currentNameExpr(profileExpr, fallbackExpr string) string {
+	return `COALESCE((SELECT a.username FROM player_aliases a WHERE a.profile_id = ` + profileExpr + `
+		ORDER BY a.last_seen DESC, a.first_seen DESC LIMIT 1), ` + fallbackExpr + `)`
+}
+
+// GetPlayers returns every player with a profile ID, most matches first.
+// Only the identity, match, round, kill and death counts are filled in.
+func (d *Database) GetPlayers() ([]models.PlayerStats, error) {
+	rows, err := d.db.Query(`
+		SELECT
+			s.profile_id,
+			` + currentNameExpr("s.profile_id", "MAX(s.username)") + ` as username,
+			COUNT(DISTINCT s.match_id) as matches,
+			COUNT(*) as rounds,
+			SUM(s.kills) as kills,
+			SUM(CASE WHEN s.died THEN 1 ELSE 0 END) as deaths
+		` + filteredRoundStatsFrom + `
+		WHERE s.profile_id != ''
+		GROUP BY s.profile_id
+		ORDER BY matches DESC, username
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []models.PlayerStats
+	for rows.Next() {
+		var s models.PlayerStats
+		if err := rows.Scan(&s.ProfileID, &s.Username, &s.MatchesPlayed, &s.RoundsPlayed, &s.Kills, &s.Deaths); err != nil {
+			return nil, err
+		}
+		s.KD = kd(s.Kills, s.Deaths)
+		players = append(players, s)
+	}
+	return players, rows.Err()
+}
+
+// GetPlayerStats returns a player's career stats across every imported match,
+// under the username they used most recently, along with every alias they
+// have been seen with. It returns nil if the profile has no recorded rounds.
+func (d *Database) GetPlayerStats(profileID string) (*models.PlayerStats, error) {
+	s := models.PlayerStats{ProfileID: profileID}
+
+	aliases, err := d.GetPlayerAliases(profileID)
+	if err != nil {
+		return nil, err
+	}
+	if len(aliases) == 0 {
+		return nil, nil
+	}
+	s.Username = aliases[0].Username
+	s.Aliases = aliases
+
+	err = d.db.QueryRow(`
+		SELECT
+			COUNT(DISTINCT s.match_id),
+			COUNT(*),
+			COALESCE(SUM(s.kills), 0),
+			COALESCE(SUM(CASE WHEN s.died THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(s.assists), 0),
+			COALESCE(SUM(s.headshots), 0),
+			COALESCE(SUM(CASE WHEN s.entry_kill THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN s.entry_death THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN s.kost THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(s.clutch_attempts), 0),
+			COALESCE(SUM(s.clutch_wins), 0),
+			COALESCE(AVG(s.rating), 0)
+		`+filteredRoundStatsFrom+`
+		WHERE s.profile_id = ?
+	`, profileID).Scan(
+		&s.MatchesPlayed, &s.RoundsPlayed, &s.Kills, &s.Deaths, &s.Assists, &s.Headshots,
+		&s.EntryKills, &s.EntryDeaths, &s.KOSTRounds, &s.ClutchAttempts, &s.ClutchWins, &s.Rating,
+	)
+	if err != nil {
+		return nil, err
+	}
+	// Aliases outlive deleted matches
+	if s.RoundsPlayed == 0 {
+		return nil, nil
+	}
+
+	err = d.db.QueryRow(`
+		SELECT COUNT(DISTINCT CASE WHEN `+playerMatchWonExpr+` THEN m.id END)
+		`+filteredRoundStatsFrom+`
+		WHERE s.profile_id = ?
+	`, profileID).Scan(&s.MatchesWon)
+	if err != nil {
+		return nil, err
+	}
+
+	s.KD = kd(s.Kills, s.Deaths)
+	s.EntryDifferential = s.EntryKills - s.EntryDeaths
+	if s.Kills > 0 {
+		s.HeadshotPercentage = float64(s.Headshots) / float64(s.Kills) * 100
+	}
+	if s.MatchesPlayed > 0 {
+		s.WinRate = float64(s.MatchesWon) / float64(s.MatchesPlayed) * 100
+	}
+	if s.RoundsPlayed > 0 {
+		s.KOST = float64(s.KOSTRounds) / float64(s.RoundsPlayed) * 100
+	}
+	if s.ClutchAttempts > 0 {
+		s.ClutchRate = float64(s.ClutchWins) / float64(s.ClutchAttempts) * 100
+	}
+	return &s, nil
+}
+
+// GetPlayerMatches returns a player's most recent matches, newest first
+func (d *Database) GetPlayerMatches(profileID string, limit int) ([]models.PlayerMatch, error) {
+	rows, err := d.db.Query(`
+		SELECT
+			s.match_id,
+			SUM(s.kills),
+			SUM(CASE WHEN s.died THEN 1 ELSE 0 END),
+			SUM(s.assists),
+			AVG(s.rating),
+			MAX(`+playerMatchWonExpr+`)
+		`+filteredRoundStatsFrom+`
+		WHERE s.profile_id = ?
+		GROUP BY s.match_id
+		ORDER BY MAX(m.timestamp) DESC
+		LIMIT ?
+	`, profileID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []models.PlayerMatch
+	var ids []int64
+	for rows.Next() {
+		var pm models.PlayerMatch
+		var id int64
+		if err := rows.Scan(&id, &pm.Kills, &pm.Deaths, &pm.Assists, &pm.Rating, &pm.Won); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		matches = append(matches, pm)
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, id := range ids {
+		m, err := d.GetMatch(id)
+		if err != nil {
+			return nil, err
+		}
+		matches[i].Match = *m
+	}
+	return matches, nil
+}
+
+// GetPlayerAliases returns every username seen with a profile, most recently
+// used first
+func (d *Database) GetPlayerAliases(profileID string) ([]models.PlayerAlias, error) {
+	rows, err := d.db.Query(`
+		SELECT profile_id, username, first_seen, last_seen
+		FROM player_aliases
+		WHERE profile_id = ?
+		ORDER BY last_seen DESC, first_seen DESC
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aliases []models.PlayerAlias
+	for rows.Next() {
+		var a models.PlayerAlias
+		if err := rows.Scan(&a.ProfileID, &a.Username, &a.FirstSeen, &a.LastSeen); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// GetProfileIDByUsername returns the profile ID most recently seen with a
+// username, or "" if there is none
+func (d *Database) GetProfileIDByUsername(username string) (string, error) {
+	var profileID string
+	err := d.db.QueryRow(`
+		SELECT profile_id FROM player_aliases
+		WHERE username = ?
+		ORDER BY last_seen DESC LIMIT 1
+	`, username).Scan(&profileID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return profileID, err
+}
+
+func kd(kills, deaths int) float64 {
+	if deaths == 0 {
+		return float64(kills)
+	}
+	return float64(kills) / float64(deaths)
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"r6-replay-recorder/models"
+)
+
+// roundPlantedExpr is whether the defuser was planted in round r. Older
+// imports may lack the plant event, so the win condition is checked too.
+const roundPlantedExpr = `(r.win_condition IN ('DefusedBomb', 'DisabledDefuser') OR EXISTS (
+	SELECT 1 FROM match_events e WHERE e.round_id = r.id AND e.event_type = 'DefuserPlantComplete'))`
+
+// GetSiteStats returns per-site win rates by side, plant success and win
+// conditions for a map, most played site first. An empty mapName returns
+// the sites of every map.
+func (d *Database) GetSiteStats(mapName string) ([]models.SiteStats, error) {
+	where := " WHERE r.site != ''"
+	args := []interface{}{}
+	if mapName != "" {
+		where += " AND m.map = ?"
+		args = append(args, mapName)
+	}
+
+	rows, err := d.db.Query(`
+		SELECT
+			m.map,
+			r.site,
+			COUNT(*) as rounds,
+			SUM(CASE WHEN r.team_role = 'Attack' THEN 1 ELSE 0 END) as attack_rounds,
+			SUM(CASE WHEN r.team_role = 'Attack' AND r.won THEN 1 ELSE 0 END) as attack_wins,
+			SUM(CASE WHEN r.team_role = 'Defense' THEN 1 ELSE 0 END) as defense_rounds,
+			SUM(CASE WHEN r.team_role = 'Defense' AND r.won THEN 1 ELSE 0 END) as defense_wins,
+			SUM(CASE WHEN `+roundPlantedExpr+` THEN 1 ELSE 0 END) as plants,
+			SUM(CASE WHEN `+roundPlantedExpr+` AND (r.team_role = 'Attack') = (r.won != 0) THEN 1 ELSE 0 END) as plants_won
+		FROM rounds r
+		JOIN matches m ON m.id = r.match_id`+where+`
+		GROUP BY m.map, r.site
+		ORDER BY m.map, rounds DESC, r.site
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []models.SiteStats
+	index := make(map[[2]string]int)
+	for rows.Next() {
+		var s models.SiteStats
+		err := rows.Scan(&s.Map, &s.Site, &s.Rounds,
+			&s.AttackRounds, &s.AttackWins, &s.DefenseRounds, &s.DefenseWins,
+			&s.Plants, &s.PlantsWon)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if s.AttackRounds > 0 {
+			s.AttackWinRate = float64(s.AttackWins) / float64(s.AttackRounds) * 100
+		}
+		if s.DefenseRounds > 0 {
+			s.DefenseWinRate = float64(s.DefenseWins) / float64(s.DefenseRounds) * 100
+		}
+		if s.Plants > 0 {
+			s.PlantSuccess = float64(s.PlantsWon) / float64(s.Plants) * 100
+		}
+		index[[2]string{s.Map, s.Site}] = len(stats)
+		stats = append(stats, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = d.db.Query(`
+		SELECT
+			m.map,
+			r.site,
+			`+roundWinConditionExpr+` as win_condition,
+			COUNT(*) as rounds,
+			SUM(CASE WHEN r.won THEN 1 ELSE 0 END) as won
+		FROM rounds r
+		JOIN matches m ON m.id = r.match_id`+where+`
+		GROUP BY m.map, r.site, 3
+		ORDER BY rounds DESC, 3
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mapName, site string
+		var wc models.WinConditionCount
+		if err := rows.Scan(&mapName, &site, &wc.WinCondition, &wc.Rounds, &wc.Won); err != nil {
+			return nil, err
+		}
+		if i, ok := index[[2]string{mapName, site}]; ok {
+			stats[i].WinConditions = append(stats[i].WinConditions, wc)
+		}
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"r6-replay-recorder/models"
+)
+
+// Filtered aggregates join player_round_stats (s) with its round (r) and
+// match (m). Rounds store their role and result from the recording team's
+// side, so these expressions flip them for players on the other team.
+const (
+	playerRoleExpr = `CASE WHEN s.team_index = m.recording_team_index THEN r.team_role
+		WHEN r.team_role = 'Attack' THEN 'Defense'
+		WHEN r.team_role = 'Defense' THEN 'Attack'
+		ELSE r.team_role END`
+	playerWonExpr = `CASE WHEN s.team_index = m.recording_team_index THEN r.won
+		ELSE NOT r.won END`
+)
+
+const filteredRoundStatsFrom = `
+	FROM player_round_stats s
+	JOIN rounds r ON r.id = s.round_id
+	JOIN matches m ON m.id = s.match_id`
+
+// filterSet reports whether a filter field should be applied
+func filterSet(v string) bool {
+	return v != "" && v != "All"
+}
+
+// sideWhere narrows the filteredRoundStatsFrom tables to rounds a player
+// spent on side, unless it is unset
+func sideWhere(side string) (string, []interface{}) {
+	if !filterSet(side) {
+		return "", nil
+	}
+	return " AND (" + playerRoleExpr + ") = ?", []interface{}{side}
+}
+
+// statsFilterWhere builds the WHERE clause for a StatsFilter over the
+// filteredRoundStatsFrom tables
+func statsFilterWhere(f models.StatsFilter) (string, []interface{}) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+
+	// Players are matched by profile, so every name they have used counts
+	if filterSet(f.Player) {
+		where += ` AND (s.username = ? OR s.profile_id IN (
+			SELECT profile_id FROM player_aliases WHERE username = ?))`
+		args = append(args, f.Player, f.Player)
+	} else {
+		where += " AND (s.profile_id = m.profile_id AND s.profile_id != '' OR s.username = m.recording_player)"
+	}
+
+	rest, restArgs := roundFilterWhere(f)
+	return where + rest, append(args, restArgs...)
+}
+
+// roundFilterWhere is the part of statsFilterWhere that doesn't pick the
+// player, to be appended to a WHERE clause
+func roundFilterWhere(f models.StatsFilter) (string, []interface{}) {
+	where := ""
+	args := []interface{}{}
+
+	sideClause, sideArgs := sideWhere(f.Role)
+	where += sideClause
+	args = append(args, sideArgs...)
+	if filterSet(f.Map) {
+		where += " AND m.map = ?"
+		args = append(args, f.Map)
+	}
+	if filterSet(f.MatchType) {
+		where += " AND m.match_type = ?"
+		args = append(args, f.MatchType)
+	}
+	// Match timestamps are stored in UTC and compared as text
+	if !f.From.IsZero() {
+		where += " AND m.timestamp >= ?"
+		args = append(args, f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		where += " AND m.timestamp < ?"
+		args = append(args, f.To.UTC())
+	}
+
+	return where, args
+}
+
+// GetOperatorStats returns per-operator picks, kills, deaths, round wins and
+// entry duels for the rounds matching filter, most picked first
+func (d *Database) GetOperatorStats(filter models.StatsFilter) ([]models.OperatorStats, error) {
+	where, args := statsFilterWhere(filter)
+	rows, err := d.db.Query(`
+		SELECT
+			s.operator,
+			COUNT(*) as picks,
+			SUM(s.kills) as kills,
+			SUM(CASE WHEN s.died THEN 1 ELSE 0 END) as deaths,
+			SUM(CASE WHEN `+playerWonExpr+` THEN 1 ELSE 0 END) as rounds_won,
+			SUM(CASE WHEN s.entry_kill THEN 1 ELSE 0 END) as entry_kills,
+			SUM(CASE WHEN s.entry_death THEN 1 ELSE 0 END) as entry_deaths
+		`+filteredRoundStatsFrom+where+`
+		GROUP BY s.operator
+		ORDER BY picks DESC, s.operator
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []models.OperatorStats
+	for rows.Next() {
+		var s models.OperatorStats
+		err := rows.Scan(&s.Operator, &s.TimesUsed, &s.Kills, &s.Deaths, &s.RoundsWon, &s.EntryKills, &s.EntryDeaths)
+		if err != nil {
+			return nil, err
+		}
+		s.RoundsLost = s.TimesUsed - s.RoundsWon
+		s.KD = kd(s.Kills, s.Deaths)
+		if s.TimesUsed > 0 {
+			s.WinRate = float64(s.RoundsWon) / float64(s.TimesUsed) * 100
+		}
+		if duels := s.EntryKills + s.EntryDeaths; duels > 0 {
+			s.EntrySuccess = float64(s.EntryKills) / float64(duels) * 100
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"time"
+
+	"r6-replay-recorder/models"
+)
+
+// roundWinConditionExpr is how round r was won. Replays from Y9S4 on only
+// record a win condition for defuser rounds, so an empty one is derived from
+// the round's stats: a fully dead team means eliminations, otherwise time
+// ran out. Rounds without stats, or with a plant that neither went off nor
+// was disabled, are Unknown.
+const roundWinConditionExpr = `CASE
+	WHEN COALESCE(r.win_condition, '') != '' THEN r.win_condition
+	WHEN NOT EXISTS (SELECT 1 FROM player_round_stats x WHERE x.round_id = r.id) THEN 'Unknown'
+	WHEN EXISTS (SELECT 1 FROM player_round_stats x WHERE x.round_id = r.id
+		GROUP BY x.team_index HAVING SUM(CASE WHEN x.died THEN 0 ELSE 1 END) = 0) THEN 'KilledOpponents'
+	WHEN ` + roundPlantedExpr + ` THEN 'Unknown'
+	ELSE 'Time' END`
+
+// winConditionWhere narrows rounds r of matches m to a map and a side of the
+// recording team, each unless unset
+func winConditionWhere(mapName, side string) (string, []interface{}) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	if filterSet(mapName) {
+		where += " AND m.map = ?"
+		args = append(args, mapName)
+	}
+	if filterSet(side) {
+		where += " AND r.team_role = ?"
+		args = append(args, side)
+	}
+	return where, args
+}
+
+// GetWinConditionStats returns how the recording team won and lost its
+// rounds on each side, optionally for one map or side
+func (d *Database) GetWinConditionStats(mapName, side string) ([]models.WinConditionStats, error) {
+	where, args := winConditionWhere(mapName, side)
+	rows, err := d.db.Query(`
+		SELECT
+			r.team_role,
+			`+roundWinConditionExpr+` as win_condition,
+			SUM(CASE WHEN r.won THEN 1 ELSE 0 END) as won,
+			SUM(CASE WHEN NOT r.won THEN 1 ELSE 0 END) as lost
+		FROM rounds r
+		JOIN matches m ON m.id = r.match_id`+where+`
+		GROUP BY r.team_role, 2
+		ORDER BY r.team_role, COUNT(*) DESC, 2
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []models.WinConditionStats
+	won := make(map[string]int)
+	lost := make(map[string]int)
+	for rows.Next() {
+		var s models.WinConditionStats
+		if err := rows.Scan(&s.Side, &s.WinCondition, &s.Won, &s.Lost); err != nil {
+			return nil, err
+		}
+		won[s.Side] += s.Won
+		lost[s.Side] += s.Lost
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range stats {
+		s := &stats[i]
+		if won[s.Side] > 0 {
+			s.WonShare = float64(s.Won) / float64(won[s.Side]) * 100
+		}
+		if lost[s.Side] > 0 {
+			s.LostShare = float64(s.Lost) / float64(lost[s.Side]) * 100
+		}
+	}
+	return stats, nil
+}
+
+// GetWinConditionTrend returns, per week, how many of the recording team's
+// lost rounds were lost to time running out on attack or to a planted
+// defuser on defense, oldest week first
+func (d *Database) GetWinConditionTrend(mapName, side string) ([]models.WinConditionTrend, error) {
+	where, args := winConditionWhere(mapName, side)
+	rows, err := d.db.Query(`
+		SELECT
+			date(m.timestamp, '-6 days', 'weekday 1') as week,
+			COUNT(*) as rounds,
+			SUM(CASE WHEN NOT r.won THEN 1 ELSE 0 END) as lost,
+			SUM(CASE WHEN NOT r.won AND (`+roundWinConditionExpr+`) = 'Time' THEN 1 ELSE 0 END) as lost_to_time,
+			SUM(CASE WHEN NOT r.won AND r.win_condition = 'DefusedBomb' THEN 1 ELSE 0 END) as lost_to_plant
+		FROM rounds r
+		JOIN matches m ON m.id = r.match_id`+where+`
+		GROUP BY week
+		ORDER BY week
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trend []models.WinConditionTrend
+	for rows.Next() {
+		var t models.WinConditionTrend
+		var week string
+		if err := rows.Scan(&week, &t.Rounds, &t.Lost, &t.LostToTime, &t.LostToPlant); err != nil {
+			return nil, err
+		}
+		t.Week, _ = time.Parse("2006-01-02", week)
+		if t.Lost > 0 {
+			t.TimeShare = float64(t.LostToTime) / float64(t.Lost) * 100
+			t.PlantShare = float64(t.LostToPlant) / float64(t.Lost) * 100
+		}
+		trend = append(trend, t)
+	}
+	return trend, rows.Err()
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"r6-replay-recorder/models"
+)
+
+func TestDerivedWinConditions(t *testing.T) {
+	db := openTestDB(t)
+	w, err := db.BeginMatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Rollback()
+
+	matchDBID, err := w.InsertMatch(&models.Match{
+		MatchID: "m1", Timestamp: time.Date(2024, 12, 4, 0, 0, 0, 0, time.UTC),
+		Map: "Bank", RecordingPlayer: "a1", ProfileID: "p1", RoundsPlayed: 6,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every round is on attack; dead lists who died, nil for a round
+	// stored without stats
+	rounds := []struct {
+		won          bool
+		winCondition string
+		dead         []string
+		planted      bool
+	}{
+		{won: true, winCondition: "KilledOpponents", dead: []string{"b1", "b2"}},
+		{won: true, dead: []string{"a1", "b1", "b2"}},
+		{won: false, dead: []string{"b1"}},
+		{won: false},
+		{won: false, dead: []string{"a1"}, planted: true},
+		{won: false, winCondition: "DefusedBomb", dead: []string{}, planted: true},
+	}
+	for i, tr := range rounds {
+		roundDBID, err := w.InsertRound(&models.Round{
+			MatchID: matchDBID, RoundNumber: i + 1, Site: "CEO Office", TeamRole: "Attack",
+			Won: tr.won, WinCondition: tr.winCondition,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tr.dead != nil {
+			for _, username := range []string{"a1", "a2", "b1", "b2"} {
+				died := false
+				for _, d := range tr.dead {
+					died = died || d == username
+				}
+				team := 0
+				if username[0] == 'b' {
+					team = 1
+				}
+				if err := w.InsertPlayerRoundStats(&models.PlayerRoundStats{
+					RoundID: roundDBID, MatchID: matchDBID, Username: username, TeamIndex: team, Died: died,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if tr.planted {
+			if err := w.InsertEvent(&models.MatchEvent{
+				RoundID: roundDBID, MatchID: matchDBID, EventType: "DefuserPlantComplete",
+				Time: "1:00", TimeInSeconds: 60, Username: "a2",
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := db.GetWinConditionStats("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []models.WinConditionStats
+	for _, s := range stats {
+		got = append(got, models.WinConditionStats{Side: s.Side, WinCondition: s.WinCondition, Won: s.Won, Lost: s.Lost})
+	}
+	want := []models.WinConditionStats{
+		{Side: "Attack", WinCondition: "KilledOpponents", Won: 2},
+		{Side: "Attack", WinCondition: "Unknown", Lost: 2},
+		{Side: "Attack", WinCondition: "DefusedBomb", Lost: 1},
+		{Side: "Attack", WinCondition: "Time", Lost: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetWinConditionStats = %+v, want %+v", got, want)
+	}
+
+	trend, err := db.GetWinConditionTrend("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trend) != 1 || trend[0].Rounds != 6 || trend[0].Lost != 4 || trend[0].LostToTime != 1 || trend[0].LostToPlant != 1 {
+		t.Errorf("GetWinConditionTrend = %+v, want one week of 6 rounds, 4 lost, 1 to time and 1 to a plant", trend)
+	}
+
+	sites, err := db.GetSiteStats("Bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sites) != 1 {
+		t.Fatalf("GetSiteStats = %d sites, want 1", len(sites))
+	}
+	wantSite := []models.WinConditionCount{
+		{WinCondition: "KilledOpponents", Rounds: 2, Won: 2},
+		{WinCondition: "Unknown", Rounds: 2},
+		{WinCondition: "DefusedBomb", Rounds: 1},
+		{WinCondition: "Time", Rounds: 1},
+	}
+	if !reflect.DeepEqual(sites[0].WinConditions, wantSite) {
+		t.Errorf("site win conditions = %+v, want %+v", sites[0].WinConditions, wantSite)
+	}
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"r6-replay-recorder/models"
+)
+
+// RowError reports the exact row that could not be written during a match import
+type RowError struct {
+	Table string
+	Row   string
+	Err   error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("failed to insert into %s (%s): %v", e.Table, e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// MatchWriter writes one match and all of its rounds, players, events and
+// stats inside a single transaction. Nothing is visible to other readers
+// until Commit, and Rollback discards everything written so far.
+type MatchWriter struct {
+	tx      *sql.Tx
+	matchID string
+	rounds  map[int64]int // round DB ID -> round number, for error messages
+}
+
+// BeginMatch starts a transaction for writing a single match
+func (d *Database) BeginMatch() (*MatchWriter, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin match transaction: %w", err)
+	}
+	return &MatchWriter{tx: tx, rounds: make(map[int64]int)}, nil
+}
+
+// ResetRoundStats deletes the player round stats of an imported match so
+// they can be written again. rounds are the match's stored rounds.
+func (w *MatchWriter) ResetRoundStats(match *models.Match, rounds []models.Round) error {
+	w.matchID = match.MatchID
+	for _, round := range rounds {
+		w.rounds[round.ID] = round.RoundNumber
+	}
+	if _, err := w.tx.Exec("DELETE FROM player_round_stats WHERE match_id = ?", match.ID); err != nil {
+		return fmt.Errorf("failed to clear stats of match %s: %w", match.MatchID, err)
+	}
+	return nil
+}
+
+// DeleteMatch removes a stored match and everything written for it, so a
+// more complete copy can be inserted in its place
+func (w *MatchWriter) DeleteMatch(matchID string) error {
+	w.matchID = matchID
+	if _, err := w.tx.Exec("DELETE FROM matches WHERE match_id = ?", matchID); err != nil {
+		return fmt.Errorf("failed to delete match %s: %w", matchID, err)
+	}
+	return nil
+}
+
+// InsertMatch inserts the match row and returns its database ID
+func (w *MatchWriter) InsertMatch(match *models.Match) (int64, error) {
+	w.matchID = match.MatchID
+	id, err := insertMatch(w.tx, match)
+	if err != nil {
+		return 0, &RowError{Table: "matches", Row: "match " + match.MatchID, Err: err}
+	}
+	return id, nil
+}
+
+// InsertRound inserts a round and returns its database ID
+func (w *MatchWriter) InsertRound(round *models.Round) (int64, error) {
+	id, err := insertRound(w.tx, round)
+	if err != nil {
+		return 0, &RowError{Table: "rounds", Row: fmt.Sprintf("match %s, round %d", w.matchID, round.RoundNumber), Err: err}
+	}
+	w.rounds[id] = round.RoundNumber
+	return id, nil
+}
+
+// InsertPlayer inserts a player record
+func (w *MatchWriter) InsertPlayer(player *models.Player) error {
+	if err := insertPlayer(w.tx, player); err != nil {
+		return &RowError{Table: "players", Row: w.describe(player.RoundID, "player "+player.Username), Err: err}
+	}
+	return nil
+}
+
+// InsertEvent inserts a match event
+func (w *MatchWriter) InsertEvent(event *models.MatchEvent) error {
+	if err := insertEvent(w.tx, event); err != nil {
+		return &RowError{Table: "match_events", Row: w.describe(event.RoundID, fmt.Sprintf("%s event at %s", event.EventType, event.Time)), Err: err}
+	}
+	return nil
+}
+
+// InsertPlayerRoundStats inserts player stats for a round
+func (w *MatchWriter) InsertPlayerRoundStats(stats *models.PlayerRoundStats) error {
+	if err := insertPlayerRoundStats(w.tx, stats); err != nil {
+		return &RowError{Table: "player_round_stats", Row: w.describe(stats.RoundID, "stats for "+stats.Username), Err: err}
+	}
+	return nil
+}
+
+// InsertRoundRaw stores a round's compressed decoded replay
+func (w *MatchWriter) InsertRoundRaw(roundID, matchID int64, data []byte) error {
+	_, err := w.tx.Exec("INSERT OR REPLACE INTO round_raw (round_id, match_id, data) VALUES (?, ?, ?)", roundID, matchID, data)
+	if err != nil {
+		return &RowError{Table: "round_raw", Row: w.describe(roundID, "raw round"), Err: err}
+	}
+	return nil
+}
+
+// Commit makes the whole match visible
+func (w *MatchWriter) Commit() error {
+	if err := w.tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit match %s: %w", w.matchID, err)
+	}
+	return nil
+}
+
+// Rollback discards the match. It is safe to call after Commit.
+func (w *MatchWriter) Rollback() error {
+	err := w.tx.Rollback()
+	if err == sql.ErrTxDone {
+		return nil
+	}
+	return err
+}
+
+func (w *MatchWriter) describe(roundID int64, what string) string {
+	return fmt.Sprintf("match %s, round %d, %s", w.matchID, w.rounds[roundID], what)
+}
@@ -14,12 +14,15 @@ type Match struct {
 	Map             string    `json:"map"`
 	RecordingPlayer string    `json:"recordingPlayer"`
 	ProfileID       string    `json:"profileId"`
-	TeamScore       int       `json:"teamScore"`
-	OpponentScore   int       `json:"opponentScore"`
-	Won             bool      `json:"won"`
-	RoundsPlayed    int       `json:"roundsPlayed"`
-	ImportedAt      time.Time `json:"importedAt"`
-	FilePath        string    `json:"filePath"`
+	// RecordingTeamIndex is the team the recording player was on. TeamScore,
+	// OpponentScore and Won, and each round's role and result, are from its side.
+	RecordingTeamIndex int       `json:"recordingTeamIndex"`
+	TeamScore          int       `json:"teamScore"`
+	OpponentScore      int       `json:"opponentScore"`
+	Won                bool      `json:"won"`
+	RoundsPlayed       int       `json:"roundsPlayed"`
+	ImportedAt         time.Time `json:"importedAt"`
+	FilePath           string    `json:"filePath"`
 }
 
 // Round represents a single round within a match
@@ -66,6 +69,7 @@ type PlayerRoundStats struct {
 	ID                 int64   `json:"id"`
 	RoundID            int64   `json:"roundId"`
 	MatchID            int64   `json:"matchId"`
+	ProfileID          string  `json:"profileId"`
 	Username           string  `json:"username"`
 	TeamIndex          int     `json:"teamIndex"`
 	Operator           string  `json:"operator"`
@@ -98,13 +102,22 @@ type PlayerRoundStats struct {
 	QuadKills   int  `json:"quadKills"`
 	Ace         bool `json:"ace"`
 
-	// Trading stats
+	// Trading stats: kills that avenged a teammate, and deaths a teammate avenged
 	TradeKills  int `json:"tradeKills"`
 	TradeDeaths int `json:"tradeDeaths"`
 
 	// Survival stats
-	SurvivalTime float64 `json:"survivalTime"`
+	SurvivalTime float64 `json:"survivalTime"` // seconds alive in the action phase
 	Survived     bool    `json:"survived"`
+
+	// KOST is true if the player got a kill, planted or defused, survived or was traded
+	KOST bool `json:"kost"`
+
+	// Rating is the SiegeScope Rating of the round; see analysis.Rating
+	Rating float64 `json:"rating"`
+
+	// AnalysisVersion is the parser.AnalysisVersion these stats were computed with
+	AnalysisVersion int `json:"analysisVersion"`
 }
 
 // PlayerStats aggregated stats for a player across matches
@@ -119,7 +132,38 @@ type PlayerStats struct {
 	Headshots          int     `json:"headshots"`
 	HeadshotPercentage float64 `json:"headshotPercentage"`
 	KD                 float64 `json:"kd"`
+	MatchesWon         int     `json:"matchesWon"`
 	WinRate            float64 `json:"winRate"`
+	EntryKills         int     `json:"entryKills"`
+	EntryDeaths        int     `json:"entryDeaths"`
+	EntryDifferential  int     `json:"entryDifferential"` // entry kills minus entry deaths
+	KOSTRounds         int     `json:"kostRounds"`
+	KOST               float64 `json:"kost"` // percentage of rounds
+	ClutchAttempts     int     `json:"clutchAttempts"`
+	ClutchWins         int     `json:"clutchWins"`
+	ClutchRate         float64 `json:"clutchRate"`
+	Rating             float64 `json:"rating"` // average SiegeScope Rating per round
+
+	// Aliases are every username seen with this profile, most recent first
+	Aliases []PlayerAlias `json:"aliases,omitempty"`
+}
+
+// PlayerAlias is a username a profile has been seen with
+type PlayerAlias struct {
+	ProfileID string    `json:"profileId"`
+	Username  string    `json:"username"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// PlayerMatch is one match from a player's point of view
+type PlayerMatch struct {
+	Match   Match   `json:"match"`
+	Kills   int     `json:"kills"`
+	Deaths  int     `json:"deaths"`
+	Assists int     `json:"assists"`
+	Rating  float64 `json:"rating"` // average SiegeScope Rating over the match's rounds
+	Won     bool    `json:"won"`    // the player's team won
 }
 
 // MapStats aggregated stats for a specific map
@@ -130,22 +174,119 @@ type MapStats struct {
 	Losses    int     `json:"losses"`
 	WinRate   float64 `json:"winRate"`
 	AvgRounds float64 `json:"avgRounds"`
+
+	// Round results by the recording team's side
+	AttackRounds   int     `json:"attackRounds"`
+	AttackWins     int     `json:"attackWins"`
+	AttackWinRate  float64 `json:"attackWinRate"`
+	DefenseRounds  int     `json:"defenseRounds"`
+	DefenseWins    int     `json:"defenseWins"`
+	DefenseWinRate float64 `json:"defenseWinRate"`
+}
+
+// SiteStats aggregated stats for a bomb site on a map. Attack and defense
+// rounds and wins are from the recording team's side.
+type SiteStats struct {
+	Map            string  `json:"map"`
+	Site           string  `json:"site"`
+	Rounds         int     `json:"rounds"`
+	AttackRounds   int     `json:"attackRounds"`
+	AttackWins     int     `json:"attackWins"`
+	AttackWinRate  float64 `json:"attackWinRate"`
+	DefenseRounds  int     `json:"defenseRounds"`
+	DefenseWins    int     `json:"defenseWins"`
+	DefenseWinRate float64 `json:"defenseWinRate"`
+	Plants         int     `json:"plants"`       // rounds the defuser was planted, by either team
+	PlantsWon      int     `json:"plantsWon"`    // planted rounds the attackers went on to win
+	PlantSuccess   float64 `json:"plantSuccess"` // PlantsWon as a percentage of Plants
+
+	// WinConditions are how rounds on the site ended, most common first
+	WinConditions []WinConditionCount `json:"winConditions"`
+}
+
+// WinConditionCount is how often rounds ended a certain way
+type WinConditionCount struct {
+	WinCondition string `json:"winCondition"`
+	Rounds       int    `json:"rounds"`
+	Won          int    `json:"won"` // rounds the recording team won
+}
+
+// WinConditionStats is how the recording team's rounds on a side were won
+// and lost with one win condition
+type WinConditionStats struct {
+	Side         string  `json:"side"`
+	WinCondition string  `json:"winCondition"`
+	Won          int     `json:"won"`
+	Lost         int     `json:"lost"`
+	WonShare     float64 `json:"wonShare"`  // percentage of the side's rounds won
+	LostShare    float64 `json:"lostShare"` // percentage of the side's rounds lost
+}
+
+// WinConditionTrend is the share of rounds lost to time or to a planted
+// defuser in one week
+type WinConditionTrend struct {
+	Week        time.Time `json:"week"` // Monday the week starts on, UTC
+	Rounds      int       `json:"rounds"`
+	Lost        int       `json:"lost"`
+	LostToTime  int       `json:"lostToTime"`
+	LostToPlant int       `json:"lostToPlant"`
+	TimeShare   float64   `json:"timeShare"`  // LostToTime as a percentage of Lost
+	PlantShare  float64   `json:"plantShare"` // LostToPlant as a percentage of Lost
 }
 
 // OperatorStats aggregated stats for an operator
 type OperatorStats struct {
-	Operator   string  `json:"operator"`
-	TimesUsed  int     `json:"timesUsed"`
-	Kills      int     `json:"kills"`
-	Deaths     int     `json:"deaths"`
-	KD         float64 `json:"kd"`
-	RoundsWon  int     `json:"roundsWon"`
-	RoundsLost int     `json:"roundsLost"`
-	WinRate    float64 `json:"winRate"`
+	Operator     string  `json:"operator"`
+	TimesUsed    int     `json:"timesUsed"`
+	Kills        int     `json:"kills"`
+	Deaths       int     `json:"deaths"`
+	KD           float64 `json:"kd"`
+	RoundsWon    int     `json:"roundsWon"`
+	RoundsLost   int     `json:"roundsLost"`
+	WinRate      float64 `json:"winRate"`
+	EntryKills   int     `json:"entryKills"`
+	EntryDeaths  int     `json:"entryDeaths"`
+	EntrySuccess float64 `json:"entrySuccess"` // entry kills as a percentage of opening duels taken
+}
+
+// OpeningDuelStats aggregates the first kill of each round. Only the fields
+// the stats are grouped by are set among ProfileID, Username, Operator, Side,
+// Map and Site. Won and Lost are opening kills and opening deaths.
+type OpeningDuelStats struct {
+	ProfileID string `json:"profileId,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Operator  string `json:"operator,omitempty"`
+	Side      string `json:"side,omitempty"`
+	Map       string `json:"map,omitempty"`
+	Site      string `json:"site,omitempty"`
+
+	Duels        int     `json:"duels"`
+	Won          int     `json:"won"`
+	Lost         int     `json:"lost"`
+	Differential int     `json:"differential"` // Won minus Lost
+	WinRate      float64 `json:"winRate"`      // Won as a percentage of Duels
+
+	// Round results after winning and after losing the opening duel
+	RoundsWonAfterWin     int     `json:"roundsWonAfterWin"`
+	RoundWinRateAfterWin  float64 `json:"roundWinRateAfterWin"`
+	RoundsWonAfterLoss    int     `json:"roundsWonAfterLoss"`
+	RoundWinRateAfterLoss float64 `json:"roundWinRateAfterLoss"`
+}
+
+// StatsFilter narrows the rounds an aggregate is computed over. Empty or
+// "All" fields and zero times are not filtered on.
+type StatsFilter struct {
+	Player    string    `json:"player,omitempty"` // any username the player has used; empty means the recording player
+	Role      string    `json:"role,omitempty"`   // Attack or Defense, from the player's side
+	Map       string    `json:"map,omitempty"`
+	MatchType string    `json:"matchType,omitempty"`
+	From      time.Time `json:"from,omitempty"`
+	To        time.Time `json:"to,omitempty"`
 }
 
 // ClutchStats aggregated clutch statistics
 type ClutchStats struct {
+	ProfileID    string  `json:"profileId,omitempty"`
 	Username     string  `json:"username"`
 	Clutch1v1    int     `json:"clutch1v1"`
 	Clutch1v1Won int     `json:"clutch1v1Won"`
@@ -162,6 +303,7 @@ type ClutchStats struct {
 
 // DefuserStats aggregated defuser statistics
 type DefuserStats struct {
+	ProfileID        string  `json:"profileId,omitempty"`
 	Username         string  `json:"username"`
 	Plants           int     `json:"plants"`
 	Defuses          int     `json:"defuses"`
@@ -169,6 +311,25 @@ type DefuserStats struct {
 	PlantSuccessRate float64 `json:"plantSuccessRate"`
 }
 
+// KOSTStats aggregated KOST and survival statistics
+type KOSTStats struct {
+	ProfileID       string  `json:"profileId,omitempty"`
+	Username        string  `json:"username"`
+	Rounds          int     `json:"rounds"`
+	KOSTRounds      int     `json:"kostRounds"`
+	KOSTRate        float64 `json:"kostRate"`
+	AvgSurvivalTime float64 `json:"avgSurvivalTime"`
+}
+
+// RatingStats aggregated SiegeScope Rating
+type RatingStats struct {
+	ProfileID string  `json:"profileId,omitempty"`
+	Username  string  `json:"username"`
+	Rounds    int     `json:"rounds"`
+	Rating    float64 `json:"rating"` // average per round
+	Best      float64 `json:"best"`   // best single match average
+}
+
 // Settings represents user application settings
 type Settings struct {
 	ID              int64  `json:"id"`
@@ -177,5 +338,15 @@ type Settings struct {
 	Theme           string `json:"theme"`
 	StartMinimized  bool   `json:"startMinimized"`
 	StartWithSystem bool   `json:"startWithSystem"`
+	WatchPolling    bool   `json:"watchPolling"`
 	APIKey          string `json:"api_key"`
+
+	Analysis AnalysisConfig `json:"analysis"`
+}
+
+// AnalysisConfig holds the tunable windows used to derive player round stats.
+// Changing them only affects matches imported or re-analyzed afterwards.
+type AnalysisConfig struct {
+	TradeWindow     float64 `json:"tradeWindow"`     // seconds a teammate has to avenge a death
+	MultiKillWindow float64 `json:"multiKillWindow"` // most seconds between kills of a multi-kill
 }
@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"sync"
+
+	"r6-replay-recorder/database"
+	"r6-replay-recorder/models"
+)
+
+// ImportResult is the outcome of importing one match folder
+type ImportResult struct {
+	Folder string
+	Match  *models.Match // nil if the folder was skipped or failed
+	Err    error
+}
+
+// ImportProgress is reported after each folder finishes
+type ImportProgress struct {
+	Done     int
+	Total    int
+	Imported int
+	Skipped  int
+	Failed   int
+	Last     ImportResult
+}
+
+// ImportMany imports match folders using several decode workers that feed a
+// single database writer, so .rec parsing runs in parallel while SQLite only
+// ever sees one writer. progress (may be nil) is called from the writer
+// goroutine after every folder. Cancelling ctx rolls back the match being
+// written; matches already written are kept and ctx.Err() is returned along
+// with the counts so far.
+func (p *Parser) ImportMany(ctx context.Context, folders []string, workers int, progress func(ImportProgress)) (ImportProgress, error) {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(folders) {
+		workers = len(folders)
+	}
+
+	type decoded struct {
+		folder string
+		state  *database.LedgerEntry
+		dm     *decodedMatch
+		err    error
+	}
+
+	jobs := make(chan string)
+	results := make(chan decoded, workers)
+
+	// Feed folders until they run out or the import is cancelled
+	go func() {
+		defer close(jobs)
+		for _, folder := range folders {
+			select {
+			case jobs <- folder:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for folder := range jobs {
+				state := replayState(folder)
+				dm, err := p.decodeMatch(ctx, folder)
+				select {
+				case results <- decoded{folder: folder, state: state, dm: dm, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	status := ImportProgress{Total: len(folders)}
+	for r := range results {
+		result := ImportResult{Folder: r.folder}
+		result.Match, result.Err = p.finishImport(ctx, r.state, r.dm, r.err)
+
+		// A folder interrupted by cancellation is neither done nor failed
+		if ctx.Err() != nil && result.Match == nil {
+			break
+		}
+
+		switch {
+		case result.Err != nil:
+			log.Printf("ERROR importing %s: %v", r.folder, result.Err)
+			status.Failed++
+		case result.Match != nil:
+			status.Imported++
+		default:
+			status.Skipped++
+		}
+		status.Done++
+		status.Last = result
+
+		if progress != nil {
+			progress(status)
+		}
+	}
+
+	// Let blocked workers exit before returning
+	for range results {
+	}
+
+	return status, ctx.Err()
+}
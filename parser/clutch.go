@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"github.com/redraskal/r6-dissect/dissect"
+)
+
+// clutch is a player left as the last one alive on their team while at
+// least one enemy was still alive
+type clutch struct {
+	Username  string
+	Team      int
+	Opponents int  // enemies alive at the moment they were left alone (the N in 1vN)
+	Won       bool // their team won the round, by any win condition
+}
+
+// detectClutches replays a round's feed in order and returns every clutch in
+// it, at most one per team. A clutch starts the moment a player becomes the
+// last one alive on their team; kills, other deaths and players leaving all
+// count. Whether it was won is decided by the round result, so a clutch won
+// by planting, defusing or running down the clock is credited the same as
+// one won by killing the last enemy. winningTeam is -1 if the round has no
+// winner.
+func detectClutches(events []dissect.MatchUpdate, players []dissect.Player, winningTeam int) []clutch {
+	teamOf := make(map[string]int, len(players))
+	alive := make(map[int]map[string]bool)
+	for _, player := range players {
+		teamOf[player.Username] = player.TeamIndex
+		if alive[player.TeamIndex] == nil {
+			alive[player.TeamIndex] = make(map[string]bool)
+		}
+		alive[player.TeamIndex][player.Username] = true
+	}
+
+	var clutches []clutch
+	clutched := make(map[int]bool)
+
+	for _, event := range events {
+		var gone string
+		switch event.Type {
+		case dissect.Kill:
+			gone = event.Target
+		case dissect.Death, dissect.PlayerLeave:
+			gone = event.Username
+		default:
+			continue
+		}
+
+		team, ok := teamOf[gone]
+		if !ok || !alive[team][gone] {
+			continue
+		}
+		delete(alive[team], gone)
+
+		if clutched[team] || len(alive[team]) != 1 {
+			continue
+		}
+
+		enemies := 0
+		for t, members := range alive {
+			if t != team {
+				enemies += len(members)
+			}
+		}
+		if enemies == 0 {
+			continue
+		}
+
+		for last := range alive[team] {
+			clutches = append(clutches, clutch{
+				Username:  last,
+				Team:      team,
+				Opponents: enemies,
+				Won:       team == winningTeam,
+			})
+		}
+		clutched[team] = true
+	}
+
+	return clutches
+}
+
+// winningTeam returns the index of the team that won the round, or -1
+func winningTeam(header dissect.Header) int {
+	for i, team := range header.Teams {
+		if team.Won {
+			return i
+		}
+	}
+	return -1
+}
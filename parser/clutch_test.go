@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/redraskal/r6-dissect/dissect"
+)
+
+// testPlayers returns five players on each team: a1..a5 on team 0, b1..b5 on team 1
+func testPlayers() []dissect.Player {
+	var players []dissect.Player
+	for _, name := range []string{"a1", "a2", "a3", "a4", "a5"} {
+		players = append(players, dissect.Player{Username: name, TeamIndex: 0})
+	}
+	for _, name := range []string{"b1", "b2", "b3", "b4", "b5"} {
+		players = append(players, dissect.Player{Username: name, TeamIndex: 1})
+	}
+	return players
+}
+
+func kill(killer, victim string) dissect.MatchUpdate {
+	return dissect.MatchUpdate{Type: dissect.Kill, Username: killer, Target: victim}
+}
+
+func feed(t dissect.MatchUpdateType, username string) dissect.MatchUpdate {
+	return dissect.MatchUpdate{Type: t, Username: username}
+}
+
+func TestDetectClutches(t *testing.T) {
+	tests := []struct {
+		name     string
+		events   []dissect.MatchUpdate
+		winner   int
+		clutches []clutch
+	}{
+		{
+			name:   "no clutch when both teams keep two players",
+			events: []dissect.MatchUpdate{kill("b1", "a1"), kill("b1", "a2"), kill("b2", "a3")},
+			winner: 1,
+		},
+		{
+			name: "1v4 won by kills",
+			events: []dissect.MatchUpdate{
+				kill("b1", "a1"), kill("b1", "a2"), kill("b2", "a3"),
+				kill("a5", "b5"), kill("b2", "a4"), // a5 is left alone against b1..b4
+				kill("a5", "b1"), kill("a5", "b2"), kill("a5", "b3"), kill("a5", "b4"),
+			},
+			winner: 0,
+			clutches: []clutch{
+				{Username: "a5", Team: 0, Opponents: 4, Won: true},
+				{Username: "b4", Team: 1, Opponents: 1, Won: false},
+			},
+		},
+		{
+			name: "size is taken when the player is left alone, not at their first kill",
+			events: []dissect.MatchUpdate{
+				kill("b1", "a1"), kill("b1", "a2"), kill("b1", "a3"), kill("b1", "a4"),
+				kill("a5", "b1"), kill("a5", "b2"),
+			},
+			winner:   1,
+			clutches: []clutch{{Username: "a5", Team: 0, Opponents: 5, Won: false}},
+		},
+		{
+			name: "won by plant without killing the last enemy",
+			events: []dissect.MatchUpdate{
+				kill("b1", "a1"), kill("b1", "a2"), kill("b1", "a3"), kill("a5", "b1"), kill("b2", "a4"),
+				feed(dissect.DefuserPlantStart, "a5"), feed(dissect.DefuserPlantComplete, "a5"),
+			},
+			winner:   0,
+			clutches: []clutch{{Username: "a5", Team: 0, Opponents: 4, Won: true}},
+		},
+		{
+			name: "won on time by the last defender",
+			events: []dissect.MatchUpdate{
+				kill("a1", "b1"), kill("a1", "b2"), kill("a1", "b3"), kill("a2", "b4"),
+			},
+			winner:   1,
+			clutches: []clutch{{Username: "b5", Team: 1, Opponents: 5, Won: true}},
+		},
+		{
+			name: "suicides and players leaving count as losing a teammate",
+			events: []dissect.MatchUpdate{
+				feed(dissect.Death, "a1"), feed(dissect.PlayerLeave, "a2"),
+				kill("b1", "a3"), kill("b1", "a4"),
+			},
+			winner:   1,
+			clutches: []clutch{{Username: "a5", Team: 0, Opponents: 5, Won: false}},
+		},
+		{
+			name: "both teams can be left with one player",
+			events: []dissect.MatchUpdate{
+				kill("b1", "a1"), kill("b1", "a2"), kill("b1", "a3"), kill("b1", "a4"),
+				kill("a5", "b2"), kill("a5", "b3"), kill("a5", "b4"), kill("a5", "b5"),
+				kill("b1", "a5"),
+			},
+			winner: 1,
+			clutches: []clutch{
+				{Username: "a5", Team: 0, Opponents: 5, Won: false},
+				{Username: "b1", Team: 1, Opponents: 1, Won: true},
+			},
+		},
+		{
+			name: "the last player of a wiped team lost a 1v5",
+			events: []dissect.MatchUpdate{
+				kill("a1", "b1"), kill("a1", "b2"), kill("a1", "b3"), kill("a1", "b4"), kill("a1", "b5"),
+				feed(dissect.Death, "a2"), feed(dissect.Death, "a3"), feed(dissect.Death, "a4"),
+			},
+			winner:   0,
+			clutches: []clutch{{Username: "b5", Team: 1, Opponents: 5, Won: false}},
+		},
+		{
+			name: "no clutch when the last opponent died before the player was left alone",
+			events: []dissect.MatchUpdate{
+				kill("a1", "b1"), kill("a1", "b2"), kill("a1", "b3"), kill("a1", "b4"), kill("a1", "b5"),
+				feed(dissect.Death, "a2"), feed(dissect.Death, "a3"), feed(dissect.Death, "a4"),
+				feed(dissect.PlayerLeave, "a5"), // a1 is alone, but nobody is left to clutch against
+			},
+			winner:   0,
+			clutches: []clutch{{Username: "b5", Team: 1, Opponents: 5, Won: false}},
+		},
+		{
+			name: "a player killed twice in the feed is only counted once",
+			events: []dissect.MatchUpdate{
+				kill("b1", "a1"), kill("b1", "a1"), kill("b1", "a2"), kill("b1", "a3"),
+			},
+			winner: 1,
+		},
+		{
+			name: "a round without a winner is a lost clutch",
+			events: []dissect.MatchUpdate{
+				kill("b1", "a1"), kill("b1", "a2"), kill("b1", "a3"), kill("b1", "a4"),
+			},
+			winner:   -1,
+			clutches: []clutch{{Username: "a5", Team: 0, Opponents: 5, Won: false}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detectClutches(tt.events, testPlayers(), tt.winner)
+			if !reflect.DeepEqual(got, tt.clutches) {
+				t.Errorf("detectClutches() = %+v, want %+v", got, tt.clutches)
+			}
+		})
+	}
+}
+
+func TestCalculateAdvancedStatsClutch(t *testing.T) {
+	p := &Parser{}
+	events := []dissect.MatchUpdate{
+		kill("b1", "a1"), kill("b1", "a2"), kill("b1", "a3"), kill("b2", "a4"),
+		feed(dissect.DefuserPlantComplete, "a5"),
+	}
+
+	stats := p.calculateAdvancedStats(events, testPlayers(), nil, 0, "DefusedBomb", DefaultAnalysisConfig())
+
+	a5 := stats["a5"]
+	if a5.ClutchAttempts != 1 || a5.ClutchWins != 1 || !a5.Clutch1v5 {
+		t.Errorf("a5 = %d attempts, %d wins, 1v5 %v; want 1, 1, true", a5.ClutchAttempts, a5.ClutchWins, a5.Clutch1v5)
+	}
+	for name, s := range stats {
+		if name != "a5" && s.ClutchAttempts != 0 {
+			t.Errorf("%s has %d clutch attempts, want 0", name, s.ClutchAttempts)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+
+	"r6-replay-recorder/database"
+	"r6-replay-recorder/models"
+)
+
+// FindPendingReplayFolders is FindReplayFolders without the folders whose
+// .rec files are unchanged since the import ledger last saw them, whether
+// they were imported, already in the library or failed. It also returns how
+// many folders were skipped. Nothing is decoded, so this is cheap to call
+// on a large replay tree.
+func (p *Parser) FindPendingReplayFolders(ctx context.Context, rootPath string) ([]string, int, error) {
+	folders, err := p.FindReplayFolders(ctx, rootPath)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ledger, err := p.db.GetImportLedger()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var pending []string
+	for _, folder := range folders {
+		if entry, ok := ledger[folder]; ok {
+			if state := replayState(folder); state != nil && entry.SameFiles(state) {
+				continue
+			}
+		}
+		pending = append(pending, folder)
+	}
+
+	log.Printf("%d of %d replay folders changed since the last import", len(pending), len(folders))
+	return pending, len(folders) - len(pending), nil
+}
+
+// unchanged reports whether a folder's .rec files are exactly what the
+// import ledger recorded the last time it was imported
+func (p *Parser) unchanged(path string) bool {
+	entry, err := p.db.GetLedgerEntry(filepath.Clean(path))
+	if err != nil || entry == nil {
+		return false
+	}
+	state := replayState(path)
+	return state != nil && entry.SameFiles(state)
+}
+
+// recordImport stores the outcome of an import in the ledger. Only replays
+// that could not be decoded are recorded as failed; cancelled imports and
+// file system or database errors, such as a locked library, are not
+// recorded, so they are retried next time.
+func (p *Parser) recordImport(ctx context.Context, state *database.LedgerEntry, dm *decodedMatch, match *models.Match, err error) {
+	if state == nil || ctx.Err() != nil {
+		return
+	}
+
+	var decodeErr *decodeError
+	entry := *state
+	switch {
+	case errors.As(err, &decodeErr):
+		entry.Status = database.LedgerFailed
+		entry.Error = err.Error()
+	case err != nil:
+		return
+	case match != nil:
+		entry.Status = database.LedgerImported
+	default:
+		entry.Status = database.LedgerDuplicate
+	}
+	if dm != nil {
+		entry.MatchID = dm.match.MatchID
+	}
+
+	if err := p.db.RecordImport(&entry); err != nil {
+		log.Printf("ERROR recording %s in import ledger: %v", entry.FolderPath, err)
+	}
+}
+
+// replayState fingerprints the .rec files of a match folder, or a single
+// .rec file, by count, total size and newest modification time. It returns
+// nil if the path cannot be read.
+func replayState(path string) *database.LedgerEntry {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+
+	// Keep paths comparable with what FindReplayFolders returns
+	state := &database.LedgerEntry{FolderPath: filepath.Clean(path)}
+	add := func(fi os.FileInfo) {
+		state.FileCount++
+		state.TotalSize += fi.Size()
+		if fi.ModTime().After(state.LatestMtime) {
+			state.LatestMtime = fi.ModTime()
+		}
+	}
+
+	if !info.IsDir() {
+		add(info)
+		return state
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !isRecFile(entry.Name()) {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		add(fi)
+	}
+
+	return state
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"r6-replay-recorder/database"
+	"r6-replay-recorder/models"
+)
+
+func TestRecordImport(t *testing.T) {
+	tests := []struct {
+		name       string
+		match      *models.Match
+		err        error
+		wantStatus string // empty if nothing should be recorded
+	}{
+		{"imported", &models.Match{MatchID: "m1"}, nil, database.LedgerImported},
+		{"already in the library", nil, nil, database.LedgerDuplicate},
+		{"replay could not be decoded", nil, fmt.Errorf("round 2 (r2.rec): %w", &decodeError{errors.New("bad header")}), database.LedgerFailed},
+		{"library locked", nil, errors.New("database is locked"), ""},
+		{"replay could not be opened", nil, &os.PathError{Op: "open", Path: "r1.rec", Err: os.ErrPermission}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := database.Open(filepath.Join(t.TempDir(), "replays.db"))
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer db.Close()
+
+			folder := t.TempDir()
+			if err := os.WriteFile(filepath.Join(folder, "r1.rec"), []byte("replay"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			New(db).recordImport(context.Background(), replayState(folder), nil, tt.match, tt.err)
+
+			entry, err := db.GetLedgerEntry(filepath.Clean(folder))
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.wantStatus == "" && entry != nil:
+				t.Errorf("recorded as %s, want it left out of the ledger to be retried", entry.Status)
+			case tt.wantStatus != "" && (entry == nil || entry.Status != tt.wantStatus):
+				t.Errorf("ledger entry = %+v, want status %s", entry, tt.wantStatus)
+			}
+		})
+	}
+}
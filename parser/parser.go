@@ -1,13 +1,15 @@
 package parser
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
-	"time"
 
+	"r6-replay-recorder/analysis"
 	"r6-replay-recorder/database"
 	"r6-replay-recorder/models"
 
@@ -24,10 +26,56 @@ func New(db *database.Database) *Parser {
 	return &Parser{db: db}
 }
 
-// ImportMatch imports a match folder (containing multiple .rec files) into the database
-func (p *Parser) ImportMatch(matchFolderPath string) (*models.Match, error) {
+// ImportMatch imports a match folder (containing multiple .rec files) into the database.
+// If ctx is cancelled nothing is written and ctx.Err() is returned.
+func (p *Parser) ImportMatch(ctx context.Context, matchFolderPath string) (*models.Match, error) {
 	log.Printf("ImportMatch called with path: %s", matchFolderPath)
 
+	state := replayState(matchFolderPath)
+	dm, err := p.decodeMatch(ctx, matchFolderPath)
+	return p.finishImport(ctx, state, dm, err)
+}
+
+// ImportSingleRound imports just a single .rec file
+func (p *Parser) ImportSingleRound(ctx context.Context, recFilePath string) (*models.Match, error) {
+	log.Printf("ImportSingleRound called with path: %s", recFilePath)
+
+	state := replayState(recFilePath)
+	dm, err := p.decodeRecFiles(ctx, recFilePath, []string{recFilePath})
+	return p.finishImport(ctx, state, dm, err)
+}
+
+// finishImport writes a decoded match unless decoding failed or found a
+// duplicate, and records the outcome in the import ledger. It returns nil
+// for anything that was not newly imported.
+func (p *Parser) finishImport(ctx context.Context, state *database.LedgerEntry, dm *decodedMatch, err error) (*models.Match, error) {
+	if err == nil && dm == nil {
+		return nil, nil // No .rec files
+	}
+
+	var match *models.Match
+	if err == nil && !dm.duplicate {
+		match, err = p.writeMatch(ctx, dm)
+	}
+
+	p.recordImport(ctx, state, dm, match, err)
+	return match, err
+}
+
+// decodedMatch is a match folder that has been fully read from disk and is
+// ready to be written to the database
+type decodedMatch struct {
+	match     *models.Match
+	rounds    []*dissect.Reader
+	duplicate bool // match ID is already in the database, rounds were not read
+	replaces  bool // the stored copy has fewer rounds and is replaced by this one
+}
+
+// decodeMatch reads every round of a match folder (or a single .rec file).
+// It returns nil if the folder has no .rec files. It only touches the
+// database to check whether the match was already imported, so it is safe
+// to call from several goroutines at once.
+func (p *Parser) decodeMatch(ctx context.Context, matchFolderPath string) (*decodedMatch, error) {
 	// Check if path exists and is a directory
 	info, err := os.Stat(matchFolderPath)
 	if err != nil {
@@ -37,19 +85,11 @@ func (p *Parser) ImportMatch(matchFolderPath string) (*models.Match, error) {
 	if !info.IsDir() {
 		log.Printf("ERROR: Path is not a directory: %s", matchFolderPath)
 		// If it's a file, try to import as single round
-		if filepath.Ext(matchFolderPath) == ".rec" {
-			return p.ImportSingleRound(matchFolderPath)
+		if isRecFile(matchFolderPath) {
+			return p.decodeRecFiles(ctx, matchFolderPath, []string{matchFolderPath})
 		}
-		return nil, err
-	}
-
-	// Open the match folder
-	folder, err := os.Open(matchFolderPath)
-	if err != nil {
-		log.Printf("ERROR: Cannot open folder: %v", err)
-		return nil, err
+		return nil, nil
 	}
-	defer folder.Close()
 
 	// Check if folder contains .rec files
 	entries, err := os.ReadDir(matchFolderPath)
@@ -58,38 +98,38 @@ func (p *Parser) ImportMatch(matchFolderPath string) (*models.Match, error) {
 		return nil, err
 	}
 
-	hasRecFiles := false
+	var recFiles []string
 	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".rec" {
-			hasRecFiles = true
-			log.Printf("Found .rec file: %s", entry.Name())
-			break
+		if !entry.IsDir() && isRecFile(entry.Name()) {
+			recFiles = append(recFiles, filepath.Join(matchFolderPath, entry.Name()))
 		}
 	}
 
-	if !hasRecFiles {
+	if len(recFiles) == 0 {
 		log.Printf("WARNING: No .rec files found in folder: %s", matchFolderPath)
 		return nil, nil
 	}
 
-	// Create match reader
-	matchReader, err := dissect.NewMatchReader(folder)
-	if err != nil {
-		log.Printf("ERROR: Cannot create match reader: %v", err)
-		return nil, err
-	}
+	// Sort to ensure round order
+	sort.Strings(recFiles)
+	log.Printf("Found %d .rec files in %s", len(recFiles), matchFolderPath)
 
-	// Get the first round to access header info
-	firstRound, err := matchReader.FirstRound()
-	if err != nil {
-		log.Printf("ERROR: Cannot get first round: %v", err)
+	return p.decodeRecFiles(ctx, matchFolderPath, recFiles)
+}
+
+// decodeRecFiles reads the first round to identify the match and, unless it
+// is already imported, every remaining round. Each file is read exactly once.
+// A match stored with fewer rounds than there are files, such as one the
+// watcher imported during a long pause mid-match, is read again to replace it.
+func (p *Parser) decodeRecFiles(ctx context.Context, filePath string, recFiles []string) (*decodedMatch, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	// Read the first round fully
-	if err := firstRound.Read(); !dissect.Ok(err) {
+	firstRound, err := readRecFile(recFiles[0])
+	if err != nil {
 		log.Printf("ERROR: Cannot read first round: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("round 1 (%s): %w", filepath.Base(recFiles[0]), err)
 	}
 
 	header := firstRound.Header
@@ -100,234 +140,204 @@ func (p *Parser) ImportMatch(matchFolderPath string) (*models.Match, error) {
 		log.Printf("ERROR: Database error checking match existence: %v", err)
 		return nil, err
 	}
+	replaces := false
 	if exists {
-		log.Printf("INFO: Match %s already exists in database", header.MatchID)
-		return nil, nil // Already imported
+		stored, err := p.db.GetStoredRoundCount(header.MatchID)
+		if err != nil {
+			return nil, err
+		}
+		if stored >= len(recFiles) {
+			log.Printf("INFO: Match %s already exists in database", header.MatchID)
+			return &decodedMatch{match: &models.Match{MatchID: header.MatchID}, duplicate: true}, nil
+		}
+		log.Printf("INFO: Match %s has %d stored rounds and %d .rec files, replacing it", header.MatchID, stored, len(recFiles))
+		replaces = true
 	}
 
-	// Calculate final scores and winner from all rounds
-	teamScore, opponentScore, won := p.calculateFinalScore(matchFolderPath)
+	rounds := []*dissect.Reader{firstRound}
+	for i, recPath := range recFiles[1:] {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		log.Printf("Processing round %d: %s", i+2, recPath)
+		reader, err := readRecFile(recPath)
+		if err != nil {
+			return nil, fmt.Errorf("round %d (%s): %w", i+2, filepath.Base(recPath), err)
+		}
+		rounds = append(rounds, reader)
+	}
+
+	// Final scores and winner come from the last round, from the recording player's side
+	last := rounds[len(rounds)-1].Header
+	us := recordingTeamIndex(header)
 
 	log.Printf("Creating match record for MatchID: %s", header.MatchID)
 
-	// Create match record
 	match := &models.Match{
-		MatchID:         header.MatchID,
-		GameVersion:     header.GameVersion,
-		CodeVersion:     header.CodeVersion,
-		Timestamp:       header.Timestamp,
-		MatchType:       matchTypeToString(header.MatchType),
-		GameMode:        header.GameMode.String(),
-		Map:             header.Map.String(),
-		RecordingPlayer: header.RecordingPlayer().Username,
-		ProfileID:       header.RecordingProfileID,
-		TeamScore:       teamScore,
-		OpponentScore:   opponentScore,
-		Won:             won,
-		RoundsPlayed:    matchReader.NumRounds(),
-		FilePath:        matchFolderPath,
-	}
-
-	// Insert match
-	matchDBID, err := p.db.InsertMatch(match)
-	if err != nil {
-		log.Printf("ERROR: Cannot insert match: %v", err)
-		return nil, err
-	}
-	match.ID = matchDBID
-	log.Printf("Successfully inserted match with DB ID: %d", matchDBID)
-
-	// Import all rounds
-	if err := p.importRounds(matchFolderPath, matchDBID); err != nil {
-		// Rollback by deleting the match
-		log.Printf("ERROR: Failed to import rounds, rolling back: %v", err)
-		p.db.DeleteMatch(matchDBID)
-		return nil, err
-	}
-
-	log.Printf("Successfully imported match: %s", header.MatchID)
-	return match, nil
+		MatchID:            header.MatchID,
+		GameVersion:        header.GameVersion,
+		CodeVersion:        header.CodeVersion,
+		Timestamp:          header.Timestamp,
+		MatchType:          matchTypeToString(header.MatchType),
+		GameMode:           header.GameMode.String(),
+		Map:                header.Map.String(),
+		RecordingPlayer:    header.RecordingPlayer().Username,
+		ProfileID:          header.RecordingProfileID,
+		RecordingTeamIndex: us,
+		TeamScore:          last.Teams[us].Score,
+		OpponentScore:      last.Teams[1-us].Score,
+		Won:                last.Teams[us].Won,
+		RoundsPlayed:       len(rounds),
+		FilePath:           filePath,
+	}
+
+	return &decodedMatch{match: match, rounds: rounds, replaces: replaces}, nil
 }
 
-// ImportSingleRound imports just a single .rec file
-func (p *Parser) ImportSingleRound(recFilePath string) (*models.Match, error) {
-	log.Printf("ImportSingleRound called with path: %s", recFilePath)
+// writeMatch writes a decoded match and every round in one transaction so a
+// failure or cancellation can never leave a partial match behind. A stored
+// copy it replaces is deleted in the same transaction. It returns nil if the
+// match was imported in the meantime.
+func (p *Parser) writeMatch(ctx context.Context, dm *decodedMatch) (*models.Match, error) {
+	match := dm.match
 
-	f, err := os.Open(recFilePath)
+	exists, err := p.db.MatchExists(match.MatchID)
 	if err != nil {
-		log.Printf("ERROR: Cannot open file: %v", err)
 		return nil, err
 	}
-	defer f.Close()
+	if exists {
+		stored, err := p.db.GetStoredRoundCount(match.MatchID)
+		if err != nil {
+			return nil, err
+		}
+		if !dm.replaces || stored >= len(dm.rounds) {
+			log.Printf("INFO: Match %s already exists in database", match.MatchID)
+			return nil, nil
+		}
+	}
 
-	reader, err := dissect.NewReader(f)
+	w, err := p.db.BeginMatch()
 	if err != nil {
-		log.Printf("ERROR: Cannot create reader: %v", err)
 		return nil, err
 	}
+	defer w.Rollback()
 
-	if err := reader.Read(); !dissect.Ok(err) {
-		log.Printf("ERROR: Cannot read replay: %v", err)
-		return nil, err
+	if exists {
+		if err := w.DeleteMatch(match.MatchID); err != nil {
+			return nil, err
+		}
 	}
 
-	header := reader.Header
-
-	// Check if match already exists
-	exists, err := p.db.MatchExists(header.MatchID)
+	matchDBID, err := w.InsertMatch(match)
 	if err != nil {
+		log.Printf("ERROR: Cannot insert match: %v", err)
 		return nil, err
 	}
-	if exists {
-		log.Printf("INFO: Match %s already exists in database", header.MatchID)
-		return nil, nil
-	}
+	match.ID = matchDBID
+	log.Printf("Successfully inserted match with DB ID: %d", matchDBID)
 
-	// Determine winner from teams
-	var teamScore, opponentScore int
-	var won bool
-	teamScore = header.Teams[0].Score
-	won = header.Teams[0].Won
-	opponentScore = header.Teams[1].Score
+	// Import all rounds
+	cfg := p.AnalysisConfig()
+	for i, reader := range dm.rounds {
+		if err := ctx.Err(); err != nil {
+			log.Printf("Import of %s cancelled, rolling back", match.MatchID)
+			return nil, err
+		}
+		if err := p.importRoundFromReader(w, reader, matchDBID, i+1, cfg); err != nil {
+			log.Printf("ERROR: Failed to import rounds, rolling back: %v", err)
+			return nil, err
+		}
+	}
 
-	// Create match record
-	match := &models.Match{
-		MatchID:         header.MatchID,
-		GameVersion:     header.GameVersion,
-		CodeVersion:     header.CodeVersion,
-		Timestamp:       header.Timestamp,
-		MatchType:       matchTypeToString(header.MatchType),
-		GameMode:        header.GameMode.String(),
-		Map:             header.Map.String(),
-		RecordingPlayer: header.RecordingPlayer().Username,
-		ProfileID:       header.RecordingProfileID,
-		TeamScore:       teamScore,
-		OpponentScore:   opponentScore,
-		Won:             won,
-		RoundsPlayed:    1,
-		FilePath:        recFilePath,
-	}
-
-	matchDBID, err := p.db.InsertMatch(match)
-	if err != nil {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	match.ID = matchDBID
-
-	// Import the single round
-	if err := p.importRoundFromReader(reader, matchDBID, 1); err != nil {
-		p.db.DeleteMatch(matchDBID)
+	if err := w.Commit(); err != nil {
 		return nil, err
 	}
 
-	log.Printf("Successfully imported single round match: %s", header.MatchID)
+	log.Printf("Successfully imported match: %s", match.MatchID)
 	return match, nil
 }
 
-func (p *Parser) calculateFinalScore(matchFolderPath string) (teamScore, opponentScore int, won bool) {
-	folder, err := os.Open(matchFolderPath)
-	if err != nil {
-		return 0, 0, false
-	}
-	defer folder.Close()
+// decodeError is a .rec file that could be opened but not decoded. Unlike
+// file system and database errors, retrying it is pointless until the file
+// changes.
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string { return e.err.Error() }
+func (e *decodeError) Unwrap() error { return e.err }
 
-	matchReader, err := dissect.NewMatchReader(folder)
+// readRecFile decodes a single .rec file. Decoding failures are returned as
+// a *decodeError.
+func readRecFile(recPath string) (*dissect.Reader, error) {
+	f, err := os.Open(recPath)
 	if err != nil {
-		return 0, 0, false
+		log.Printf("ERROR opening file: %v", err)
+		return nil, err
 	}
+	defer f.Close()
 
-	// Get the last round for final scores
-	lastRound, err := matchReader.LastRound()
+	reader, err := dissect.NewReader(f)
 	if err != nil {
-		return 0, 0, false
+		log.Printf("ERROR creating reader: %v", err)
+		return nil, &decodeError{err}
 	}
 
-	if err := lastRound.Read(); !dissect.Ok(err) {
-		return 0, 0, false
+	if err := reader.Read(); !dissect.Ok(err) {
+		log.Printf("ERROR reading replay: %v", err)
+		return nil, &decodeError{err}
 	}
 
-	teamScore = lastRound.Header.Teams[0].Score
-	won = lastRound.Header.Teams[0].Won
-	opponentScore = lastRound.Header.Teams[1].Score
-
-	return teamScore, opponentScore, won
+	return reader, nil
 }
 
-func (p *Parser) importRounds(matchFolderPath string, matchDBID int64) error {
-	// Read directory entries directly instead of using ListReplayFiles
-	entries, err := os.ReadDir(matchFolderPath)
-	if err != nil {
-		log.Printf("ERROR reading directory %s: %v", matchFolderPath, err)
-		return err
-	}
-
-	// Find all .rec files
-	var recFiles []string
-	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".rec" {
-			recFiles = append(recFiles, entry.Name())
+// recordingTeamIndex returns the team the recording player was on. Spectator
+// recordings, or players that can't be found, count as team 0.
+func recordingTeamIndex(header dissect.Header) int {
+	team := header.RecordingPlayer().TeamIndex
+	if header.RecordingProfileID != "" {
+		for _, player := range header.Players {
+			if player.ProfileID == header.RecordingProfileID {
+				team = player.TeamIndex
+				break
+			}
 		}
 	}
-
-	log.Printf("Found %d .rec files in %s", len(recFiles), matchFolderPath)
-
-	// Sort to ensure round order
-	sort.Strings(recFiles)
-
-	for i, recFile := range recFiles {
-		recPath := filepath.Join(matchFolderPath, recFile)
-		log.Printf("Processing round %d: %s", i+1, recPath)
-
-		f, err := os.Open(recPath)
-		if err != nil {
-			log.Printf("ERROR opening file: %v", err)
-			continue
-		}
-
-		reader, err := dissect.NewReader(f)
-		if err != nil {
-			log.Printf("ERROR creating reader: %v", err)
-			f.Close()
-			continue
-		}
-
-		if err := reader.Read(); !dissect.Ok(err) {
-			log.Printf("ERROR reading replay: %v", err)
-			f.Close()
-			continue
-		}
-
-		log.Printf("Successfully read round %d, importing...", i+1)
-		if err := p.importRoundFromReader(reader, matchDBID, i+1); err != nil {
-			log.Printf("ERROR importing round: %v", err)
-		}
-		f.Close()
+	if team != 1 {
+		return 0
 	}
+	return 1
+}
 
-	return nil
+func isRecFile(name string) bool {
+	return strings.ToLower(filepath.Ext(name)) == ".rec"
 }
 
-func (p *Parser) importRoundFromReader(reader *dissect.Reader, matchDBID int64, roundNum int) error {
+func (p *Parser) importRoundFromReader(w *database.MatchWriter, reader *dissect.Reader, matchDBID int64, roundNum int, cfg models.AnalysisConfig) error {
 	header := reader.Header
 
-	// Determine team role and win status
+	// Determine team role and win status from the recording player's side
 	var teamRole string
 	var won bool
 	var winCondition string
 	var teamScore, opponentScore int
 
-	team0 := header.Teams[0]
-	team1 := header.Teams[1]
+	us := recordingTeamIndex(header)
+	ourTeam := header.Teams[us]
+	theirTeam := header.Teams[1-us]
 
-	teamRole = string(team0.Role)
-	won = team0.Won
-	if team0.Won {
-		winCondition = string(team0.WinCondition)
+	teamRole = string(ourTeam.Role)
+	won = ourTeam.Won
+	if ourTeam.Won {
+		winCondition = string(ourTeam.WinCondition)
 	} else {
-		winCondition = string(team1.WinCondition)
+		winCondition = string(theirTeam.WinCondition)
 	}
-	teamScore = team0.Score
-	opponentScore = team1.Score
+	teamScore = ourTeam.Score
+	opponentScore = theirTeam.Score
 
 	// Create round record
 	round := &models.Round{
@@ -341,14 +351,14 @@ func (p *Parser) importRoundFromReader(reader *dissect.Reader, matchDBID int64,
 		OpponentScore: opponentScore,
 	}
 
-	roundDBID, err := p.db.InsertRound(round)
+	roundDBID, err := w.InsertRound(round)
 	if err != nil {
 		return err
 	}
 
 	// Import players
 	for _, player := range header.Players {
-		p.db.InsertPlayer(&models.Player{
+		err := w.InsertPlayer(&models.Player{
 			RoundID:   roundDBID,
 			MatchID:   matchDBID,
 			ProfileID: player.ProfileID,
@@ -357,6 +367,9 @@ func (p *Parser) importRoundFromReader(reader *dissect.Reader, matchDBID int64,
 			Operator:  player.Operator.String(),
 			Spawn:     player.Spawn,
 		})
+		if err != nil {
+			return err
+		}
 	}
 
 	// Import match events (kills, plants, etc.)
@@ -366,7 +379,7 @@ func (p *Parser) importRoundFromReader(reader *dissect.Reader, matchDBID int64,
 			headshot = *event.Headshot
 		}
 
-		p.db.InsertEvent(&models.MatchEvent{
+		err := w.InsertEvent(&models.MatchEvent{
 			RoundID:       roundDBID,
 			MatchID:       matchDBID,
 			EventType:     event.Type.String(),
@@ -375,14 +388,33 @@ func (p *Parser) importRoundFromReader(reader *dissect.Reader, matchDBID int64,
 			Username:      event.Username,
 			Target:        event.Target,
 			Headshot:      headshot,
+			Message:       event.Message,
 		})
+		if err != nil {
+			return err
+		}
 	}
 
-	// Get all match events
+	// Keep the whole decoded round so it can be re-analyzed without the .rec file
+	raw, err := encodeRound(reader)
+	if err != nil {
+		return fmt.Errorf("failed to encode round %d: %w", roundNum, err)
+	}
+	if err := w.InsertRoundRaw(roundDBID, matchDBID, raw); err != nil {
+		return err
+	}
+
+	return p.writeRoundStats(w, reader, matchDBID, roundDBID, winCondition, cfg)
+}
+
+// writeRoundStats derives every player's stats for a round from its feed and
+// writes them. It is shared by import and re-analysis.
+func (p *Parser) writeRoundStats(w *database.MatchWriter, reader *dissect.Reader, matchDBID, roundDBID int64, winCondition string, cfg models.AnalysisConfig) error {
+	header := reader.Header
 	events := reader.MatchFeedback
 
 	// Analyze events for advanced stats
-	playerAdvancedStats := p.calculateAdvancedStats(events, header.Players, reader.PlayerStats())
+	playerAdvancedStats := p.calculateAdvancedStats(events, header.Players, reader.PlayerStats(), winningTeam(header), winCondition, cfg)
 
 	// Import player round stats with advanced stats
 	for username, advStats := range playerAdvancedStats {
@@ -399,20 +431,23 @@ func (p *Parser) importRoundFromReader(reader *dissect.Reader, matchDBID int64,
 			continue
 		}
 
-		// Find operator and team
+		// Find profile, operator and team
+		profileID := ""
 		operator := ""
 		teamIndex := 0
 		for _, player := range header.Players {
 			if player.Username == username {
+				profileID = player.ProfileID
 				operator = player.Operator.String()
 				teamIndex = player.TeamIndex
 				break
 			}
 		}
 
-		p.db.InsertPlayerRoundStats(&models.PlayerRoundStats{
+		stat := &models.PlayerRoundStats{
 			RoundID:            roundDBID,
 			MatchID:            matchDBID,
+			ProfileID:          profileID,
 			Username:           username,
 			TeamIndex:          teamIndex,
 			Operator:           operator,
@@ -444,7 +479,14 @@ func (p *Parser) importRoundFromReader(reader *dissect.Reader, matchDBID int64,
 			TradeDeaths:    advStats.TradeDeaths,
 			SurvivalTime:   advStats.SurvivalTime,
 			Survived:       advStats.Survived,
-		})
+			KOST:           advStats.KOST,
+
+			AnalysisVersion: AnalysisVersion,
+		}
+		stat.Rating = analysis.Rating(stat)
+		if err := w.InsertPlayerRoundStats(stat); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -476,7 +518,7 @@ type advancedPlayerStats struct {
 	KOST           bool
 }
 
-func (p *Parser) calculateAdvancedStats(events []dissect.MatchUpdate, players []dissect.Player, baseStats []dissect.PlayerRoundStats) map[string]*advancedPlayerStats {
+func (p *Parser) calculateAdvancedStats(events []dissect.MatchUpdate, players []dissect.Player, baseStats []dissect.PlayerRoundStats, winner int, winCondition string, cfg models.AnalysisConfig) map[string]*advancedPlayerStats {
 	stats := make(map[string]*advancedPlayerStats)
 
 	// Initialize stats for all players
@@ -486,23 +528,14 @@ func (p *Parser) calculateAdvancedStats(events []dissect.MatchUpdate, players []
 		}
 	}
 
-	// Track alive players for clutch detection
-	alivePlayers := make(map[int][]string) // teamIndex -> []username
+	teams := make(map[string]int, len(players))
 	for _, player := range players {
-		alivePlayers[player.TeamIndex] = append(alivePlayers[player.TeamIndex], player.Username)
+		teams[player.Username] = player.TeamIndex
 	}
+	feed := analysisEvents(events)
 
-	// Track kills per player for multi-kill detection
+	// Track kills per player for ace detection
 	killCounts := make(map[string]int)
-	killTimestamps := make(map[string][]float64)
-
-	// Track recent deaths for trade detection (within 3 seconds)
-	type deathEvent struct {
-		victim string
-		killer string
-		time   float64
-	}
-	recentDeaths := []deathEvent{}
 
 	// Process events chronologically
 	for _, event := range events {
@@ -510,84 +543,7 @@ func (p *Parser) calculateAdvancedStats(events []dissect.MatchUpdate, players []
 
 		switch eventType {
 		case "Kill":
-			killer := event.Username
-			victim := event.Target
-			eventTime := event.TimeInSeconds
-
-			// Remove victim from alive players
-			for team := range alivePlayers {
-				for i, player := range alivePlayers[team] {
-					if player == victim {
-						alivePlayers[team] = append(alivePlayers[team][:i], alivePlayers[team][i+1:]...)
-						break
-					}
-				}
-			}
-
-			// Track kill for multi-kill detection
-			killCounts[killer]++
-			killTimestamps[killer] = append(killTimestamps[killer], eventTime)
-
-			// Check for trades (kill within 3 seconds of teammate death)
-			for i := len(recentDeaths) - 1; i >= 0; i-- {
-				rd := recentDeaths[i]
-				timeDiff := eventTime - rd.time
-
-				if timeDiff > 3.0 {
-					// Remove old deaths
-					recentDeaths = recentDeaths[i+1:]
-					break
-				}
-
-				// Check if this kill avenges a teammate
-				if rd.killer == victim && killer != victim {
-					if stats[killer] != nil {
-						stats[killer].TradeKills++
-					}
-					if stats[victim] != nil {
-						stats[victim].TradeDeaths++
-					}
-				}
-			}
-
-			// Add to recent deaths
-			recentDeaths = append(recentDeaths, deathEvent{
-				victim: victim,
-				killer: killer,
-				time:   eventTime,
-			})
-
-			// Check for clutch situations
-			killerTeam := p.getPlayerTeam(killer, players)
-			if killerTeam != -1 {
-				aliveOnTeam := len(alivePlayers[killerTeam])
-				enemyTeam := 1 - killerTeam
-				aliveEnemies := len(alivePlayers[enemyTeam])
-
-				if aliveOnTeam == 1 && aliveEnemies > 0 {
-					// Player is in a 1vX situation
-					if stats[killer] != nil {
-						stats[killer].ClutchAttempts++
-
-						if aliveEnemies == 1 {
-							stats[killer].Clutch1v1 = true
-						} else if aliveEnemies == 2 {
-							stats[killer].Clutch1v2 = true
-						} else if aliveEnemies == 3 {
-							stats[killer].Clutch1v3 = true
-						} else if aliveEnemies == 4 {
-							stats[killer].Clutch1v4 = true
-						} else if aliveEnemies == 5 {
-							stats[killer].Clutch1v5 = true
-						}
-
-						// Check if clutch was won (no more enemies alive after this kill)
-						if aliveEnemies == 1 {
-							stats[killer].ClutchWins++
-						}
-					}
-				}
-			}
+			killCounts[event.Username]++
 
 		case "DefuserPlantStart", "DefuserPlantComplete":
 			if event.Username != "" && stats[event.Username] != nil {
@@ -606,22 +562,45 @@ func (p *Parser) calculateAdvancedStats(events []dissect.MatchUpdate, players []
 		}
 	}
 
-	// Calculate multi-kills (kills within 10 seconds of each other)
-	for username, timestamps := range killTimestamps {
-		if len(timestamps) < 2 {
+	// Clutches
+	for _, c := range detectClutches(events, players, winner) {
+		stat := stats[c.Username]
+		if stat == nil {
 			continue
 		}
+		stat.ClutchAttempts++
+		if c.Won {
+			stat.ClutchWins++
+		}
+		switch c.Opponents {
+		case 1:
+			stat.Clutch1v1 = true
+		case 2:
+			stat.Clutch1v2 = true
+		case 3:
+			stat.Clutch1v3 = true
+		case 4:
+			stat.Clutch1v4 = true
+		case 5:
+			stat.Clutch1v5 = true
+		}
+	}
 
-		consecutiveKills := 1
-		for i := 1; i < len(timestamps); i++ {
-			if timestamps[i]-timestamps[i-1] <= 10.0 {
-				consecutiveKills++
-			} else {
-				p.recordMultiKill(stats[username], consecutiveKills)
-				consecutiveKills = 1
-			}
+	// Trades: a teammate avenging a teammate within the trade window
+	for _, t := range analysis.RoundTrades(feed, teams, cfg.TradeWindow) {
+		if stats[t.Avenger] != nil {
+			stats[t.Avenger].TradeKills++
+		}
+		if stats[t.Victim] != nil {
+			stats[t.Victim].TradeDeaths++
+		}
+	}
+
+	// Multi-kills: kills no more than the multi-kill window apart
+	for username, streaks := range analysis.KillStreaks(feed, cfg.MultiKillWindow) {
+		for _, n := range streaks {
+			p.recordMultiKill(stats[username], n)
 		}
-		p.recordMultiKill(stats[username], consecutiveKills)
 
 		// Check for ace
 		if killCounts[username] >= 5 && stats[username] != nil {
@@ -649,35 +628,36 @@ func (p *Parser) calculateAdvancedStats(events []dissect.MatchUpdate, players []
 		}
 	}
 
-	// Calculate survival stats
+	// Survival and KOST
+	kills := make(map[string]int, len(baseStats))
 	for _, baseStat := range baseStats {
-		if stat, exists := stats[baseStat.Username]; exists {
-			stat.Survived = !baseStat.Died
-			// Survival time would need to be calculated from death event timestamp
-		}
+		kills[baseStat.Username] = baseStat.Kills
 	}
-
-	for username, stat := range stats {
-		// Find base stats
-		for _, baseStat := range baseStats {
-			if baseStat.Username == username {
-				// KOST = Kill OR Objective (plant/defuse) OR Survived OR Traded
-				hasKill := baseStat.Kills > 0
-				hasObjective := stat.DefuserPlants > 0 || stat.DefuserDefuses > 0
-				survived := !baseStat.Died
-				traded := stat.TradeDeaths > 0 // If player was traded, they contributed
-
-				stat.KOST = hasKill || hasObjective || survived || traded
-				stat.Survived = survived
-				break
-			}
-		}
+	for username, survival := range analysis.RoundSurvival(feed, teams, winCondition, cfg.TradeWindow) {
+		stat := stats[username]
+		stat.SurvivalTime = survival.Time
+		stat.Survived = survival.Survived
+		stat.KOST = analysis.KOST(kills[username], stat.DefuserPlants > 0 || stat.DefuserDefuses > 0, survival)
 	}
 
 	return stats
 
 }
 
+// analysisEvents converts a round's feed for the analysis package
+func analysisEvents(events []dissect.MatchUpdate) []analysis.Event {
+	converted := make([]analysis.Event, len(events))
+	for i, event := range events {
+		converted[i] = analysis.Event{
+			Type:     event.Type.String(),
+			Time:     event.TimeInSeconds,
+			Username: event.Username,
+			Target:   event.Target,
+		}
+	}
+	return converted
+}
+
 func (p *Parser) recordMultiKill(stat *advancedPlayerStats, count int) {
 	if stat == nil {
 		return
@@ -699,15 +679,6 @@ func (p *Parser) recordMultiKill(stat *advancedPlayerStats, count int) {
 	}
 }
 
-func (p *Parser) getPlayerTeam(username string, players []dissect.Player) int {
-	for _, player := range players {
-		if player.Username == username {
-			return player.TeamIndex
-		}
-	}
-	return -1
-}
-
 // matchTypeToString converts MatchType to a readable string
 func matchTypeToString(mt dissect.MatchType) string {
 	switch mt {
@@ -726,9 +697,12 @@ func matchTypeToString(mt dissect.MatchType) string {
 	}
 }
 
-// FindReplayFolders scans a directory for R6 replay match folders
+// FindReplayFolders scans a directory for R6 replay match folders.
+// The scan stops early with ctx.Err() if ctx is cancelled. It ignores the
+// import ledger, so folder detection and forced imports see every folder;
+// imports that should skip unchanged folders use FindPendingReplayFolders.
 // IMPROVED VERSION: More flexible folder detection with recursive search
-func (p *Parser) FindReplayFolders(rootPath string) ([]string, error) {
+func (p *Parser) FindReplayFolders(ctx context.Context, rootPath string) ([]string, error) {
 	log.Printf("FindReplayFolders called with rootPath: %s", rootPath)
 
 	var folders []string
@@ -741,6 +715,9 @@ func (p *Parser) FindReplayFolders(rootPath string) ([]string, error) {
 
 	// Walk the directory tree
 	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if err != nil {
 			log.Printf("ERROR walking path %s: %v", path, err)
 			return nil // Continue walking despite errors
@@ -790,11 +767,8 @@ func containsRecFiles(dirPath string) (bool, error) {
 	}
 
 	for _, entry := range entries {
-		if !entry.IsDir() {
-			ext := strings.ToLower(filepath.Ext(entry.Name()))
-			if ext == ".rec" {
-				return true, nil
-			}
+		if !entry.IsDir() && isRecFile(entry.Name()) {
+			return true, nil
 		}
 	}
 
@@ -811,53 +785,3 @@ func GetDefaultReplayPath() string {
 	// Default Windows path for R6 replays
 	return filepath.Join(home, "Documents", "My Games", "Rainbow Six - Siege", "replays")
 }
-
-// WatchFolder watches a folder for new replay files (simplified polling version)
-type FolderWatcher struct {
-	path     string
-	parser   *Parser
-	stopChan chan struct{}
-	interval time.Duration
-}
-
-// NewFolderWatcher creates a new folder watcher
-func NewFolderWatcher(path string, parser *Parser, interval time.Duration) *FolderWatcher {
-	return &FolderWatcher{
-		path:     path,
-		parser:   parser,
-		stopChan: make(chan struct{}),
-		interval: interval,
-	}
-}
-
-// Start begins watching for new replays
-func (fw *FolderWatcher) Start(onNewMatch func(*models.Match)) {
-	go func() {
-		ticker := time.NewTicker(fw.interval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-fw.stopChan:
-				return
-			case <-ticker.C:
-				folders, err := fw.parser.FindReplayFolders(fw.path)
-				if err != nil {
-					continue
-				}
-
-				for _, folder := range folders {
-					match, err := fw.parser.ImportMatch(folder)
-					if err == nil && match != nil {
-						onNewMatch(match)
-					}
-				}
-			}
-		}
-	}()
-}
-
-// Stop stops watching
-func (fw *FolderWatcher) Stop() {
-	close(fw.stopChan)
-}
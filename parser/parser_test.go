@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"r6-replay-recorder/database"
+	"r6-replay-recorder/models"
+
+	"github.com/redraskal/r6-dissect/dissect"
+)
+
+func TestWriteMatchReplacesPartialMatch(t *testing.T) {
+	db, err := database.Open(filepath.Join(t.TempDir(), "replays.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	p := New(db)
+	ctx := context.Background()
+
+	first, second := testRound(), testRound()
+	second.Header.RoundNumber++
+
+	// The watcher imported the match after its first round
+	partial := &decodedMatch{match: &models.Match{MatchID: "match-1", RoundsPlayed: 1}, rounds: []*dissect.Reader{first}}
+	if m, err := p.writeMatch(ctx, partial); err != nil || m == nil {
+		t.Fatalf("writeMatch(partial) = %v, %v", m, err)
+	}
+
+	tests := []struct {
+		name       string
+		dm         *decodedMatch
+		wantRounds int
+		replaced   bool
+	}{
+		{"a copy that isn't marked as replacing is skipped",
+			&decodedMatch{match: &models.Match{MatchID: "match-1", RoundsPlayed: 2}, rounds: []*dissect.Reader{first, second}}, 1, false},
+		{"a copy with more rounds replaces it",
+			&decodedMatch{match: &models.Match{MatchID: "match-1", RoundsPlayed: 2}, rounds: []*dissect.Reader{first, second}, replaces: true}, 2, true},
+		{"a copy with no more rounds than stored is skipped",
+			&decodedMatch{match: &models.Match{MatchID: "match-1", RoundsPlayed: 1}, rounds: []*dissect.Reader{first}, replaces: true}, 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := p.writeMatch(ctx, tt.dm)
+			if err != nil {
+				t.Fatalf("writeMatch: %v", err)
+			}
+			if (m != nil) != tt.replaced {
+				t.Errorf("writeMatch returned %v, want a match: %v", m, tt.replaced)
+			}
+
+			if n, err := db.GetStoredRoundCount("match-1"); err != nil || n != tt.wantRounds {
+				t.Errorf("GetStoredRoundCount = %d, %v; want %d", n, err, tt.wantRounds)
+			}
+			if n, err := db.GetMatchCount(); err != nil || n != 1 {
+				t.Errorf("GetMatchCount = %d, %v; want 1", n, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+
+	"github.com/redraskal/r6-dissect/dissect"
+)
+
+// encodeRound compresses everything r6-dissect decoded from a round (the
+// header, the full match feedback and the scoreboard) for the round_raw table
+func encodeRound(reader *dissect.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(reader); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeRound restores a round stored by encodeRound. The result can be
+// analyzed like a freshly read replay.
+func decodeRound(data []byte) (*dissect.Reader, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var reader dissect.Reader
+	if err := json.NewDecoder(zr).Decode(&reader); err != nil {
+		return nil, err
+	}
+	return &reader, nil
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/redraskal/r6-dissect/dissect"
+)
+
+// testRound is a decoded round of match-1 that team 1 won by killing both
+// attackers, b1 with one headshot
+func testRound() *dissect.Reader {
+	headshot, bodyshot := true, false
+	return &dissect.Reader{
+		Header: dissect.Header{
+			GameVersion: "Y9S1",
+			Timestamp:   time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC),
+			Map:         dissect.Bank,
+			Site:        "CEO Office",
+			MatchID:     "match-1",
+			RoundNumber: 3,
+			Teams: [2]dissect.Team{
+				{Role: dissect.Attack},
+				{Role: dissect.Defense, Won: true, WinCondition: dissect.KilledOpponents},
+			},
+			Players: []dissect.Player{
+				{ProfileID: "p1", Username: "a1", TeamIndex: 0, Operator: dissect.Ash},
+				{ProfileID: "p2", Username: "a2", TeamIndex: 0, Operator: dissect.Ash},
+				{ProfileID: "p3", Username: "b1", TeamIndex: 1, Operator: dissect.Mute},
+			},
+		},
+		MatchFeedback: []dissect.MatchUpdate{
+			{Type: dissect.Kill, Username: "b1", Target: "a1", Headshot: &headshot, Time: "2:40", TimeInSeconds: 160},
+			{Type: dissect.Kill, Username: "b1", Target: "a2", Headshot: &bodyshot, Time: "2:10", TimeInSeconds: 130},
+		},
+		Scoreboard: dissect.Scoreboard{Players: []dissect.ScoreboardPlayer{
+			{Score: 0},
+			{Score: 10, AssistsFromRound: 1},
+			{Score: 250},
+		}},
+	}
+}
+
+func TestEncodeDecodeRound(t *testing.T) {
+	reader := testRound()
+	data, err := encodeRound(reader)
+	if err != nil {
+		t.Fatalf("encodeRound: %v", err)
+	}
+	decoded, err := decodeRound(data)
+	if err != nil {
+		t.Fatalf("decodeRound: %v", err)
+	}
+
+	if !reflect.DeepEqual(decoded.Header, reader.Header) {
+		t.Errorf("header = %+v, want %+v", decoded.Header, reader.Header)
+	}
+	if !reflect.DeepEqual(decoded.MatchFeedback, reader.MatchFeedback) {
+		t.Errorf("match feedback = %+v, want %+v", decoded.MatchFeedback, reader.MatchFeedback)
+	}
+	if got, want := decoded.PlayerStats(), reader.PlayerStats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PlayerStats() = %+v, want %+v", got, want)
+	}
+	if got := decoded.PlayerStats()[2]; got.Kills != 2 || got.Headshots != 1 {
+		t.Errorf("b1 has %d kills and %d headshots, want 2 and 1", got.Kills, got.Headshots)
+	}
+}
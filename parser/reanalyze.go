@@ -0,0 +1,263 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"r6-replay-recorder/analysis"
+	"r6-replay-recorder/models"
+
+	"github.com/redraskal/r6-dissect/dissect"
+)
+
+// AnalysisVersion identifies the rules used to derive player_round_stats.
+// Bump it whenever a change to the clutch, trade, multi-kill, KOST,
+// survival or rating logic would change stored values, so existing rows show
+// up as outdated and can be re-analyzed.
+const AnalysisVersion = 4
+
+// DefaultAnalysisConfig returns the analysis windows used when none are set
+func DefaultAnalysisConfig() models.AnalysisConfig {
+	return models.AnalysisConfig{
+		TradeWindow:     analysis.TradeWindowSeconds,
+		MultiKillWindow: analysis.MultiKillWindowSeconds,
+	}
+}
+
+// AnalysisConfig returns the analysis windows saved in settings, with
+// defaults for any that are missing or not positive
+func (p *Parser) AnalysisConfig() models.AnalysisConfig {
+	cfg := DefaultAnalysisConfig()
+	settings, err := p.db.GetSettings()
+	if err != nil {
+		log.Printf("Using default analysis windows: %v", err)
+		return cfg
+	}
+	if settings.Analysis.TradeWindow > 0 {
+		cfg.TradeWindow = settings.Analysis.TradeWindow
+	}
+	if settings.Analysis.MultiKillWindow > 0 {
+		cfg.MultiKillWindow = settings.Analysis.MultiKillWindow
+	}
+	return cfg
+}
+
+// Reanalyze re-reads an imported match from its replay files, or from the
+// rounds stored at import if the files are gone, and recomputes its player
+// round stats in one transaction. The stored rounds are also used when the
+// replay no longer holds the library's rounds. Rounds, players and events are
+// left as they are. If ctx is cancelled nothing is changed.
+func (p *Parser) Reanalyze(ctx context.Context, matchID int64) error {
+	match, err := p.db.GetMatch(matchID)
+	if err != nil {
+		return err
+	}
+
+	rounds, err := p.db.GetRoundsByMatch(match.ID)
+	if err != nil {
+		return err
+	}
+
+	var readers []*dissect.Reader
+	if match.FilePath == "" {
+		err = fmt.Errorf("match %s has no replay path", match.MatchID)
+	} else {
+		readers, err = readReplay(ctx, match.FilePath)
+		if err == nil {
+			err = checkReplayRounds(match, rounds, readers)
+		}
+	}
+	if err != nil && ctx.Err() == nil {
+		// The replay may have been deleted or changed since; use the copy kept at import
+		stored, storedErr := p.storedRounds(match.ID, rounds)
+		if storedErr != nil {
+			log.Printf("No stored rounds for match %s: %v", match.MatchID, storedErr)
+			return err
+		}
+		log.Printf("Re-analyzing match %s from stored rounds: %v", match.MatchID, err)
+		readers, err = stored, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	w, err := p.db.BeginMatch()
+	if err != nil {
+		return err
+	}
+	defer w.Rollback()
+
+	if err := w.ResetRoundStats(match, rounds); err != nil {
+		return err
+	}
+	cfg := p.AnalysisConfig()
+	for i, reader := range readers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		round := rounds[i]
+		if err := p.writeRoundStats(w, reader, match.ID, round.ID, round.WinCondition, cfg); err != nil {
+			return err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := w.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("Re-analyzed match %s", match.MatchID)
+	return nil
+}
+
+// ReanalyzeResult is the outcome of re-analyzing one match
+type ReanalyzeResult struct {
+	Match models.Match
+	Err   error
+}
+
+// ReanalyzeProgress is reported after each match finishes
+type ReanalyzeProgress struct {
+	Done    int
+	Total   int
+	Updated int
+	Failed  int
+	Last    ReanalyzeResult
+}
+
+// ReanalyzeLibrary re-analyzes every match with stats from an older
+// AnalysisVersion, or every match if all is set. progress (may be nil) is
+// called after every match. Cancelling ctx leaves the match in progress
+// untouched and returns ctx.Err() with the counts so far.
+func (p *Parser) ReanalyzeLibrary(ctx context.Context, all bool, progress func(ReanalyzeProgress)) (ReanalyzeProgress, error) {
+	var ids []int64
+	if all {
+		matches, err := p.db.GetAllMatches()
+		if err != nil {
+			return ReanalyzeProgress{}, err
+		}
+		for _, m := range matches {
+			ids = append(ids, m.ID)
+		}
+	} else {
+		var err error
+		ids, err = p.db.GetOutdatedMatchIDs(AnalysisVersion)
+		if err != nil {
+			return ReanalyzeProgress{}, err
+		}
+	}
+
+	status := ReanalyzeProgress{Total: len(ids)}
+	for _, id := range ids {
+		err := p.Reanalyze(ctx, id)
+		if ctx.Err() != nil {
+			break
+		}
+
+		result := ReanalyzeResult{Err: err}
+		if match, err := p.db.GetMatch(id); err == nil {
+			result.Match = *match
+		} else {
+			result.Match.ID = id
+		}
+
+		if err != nil {
+			log.Printf("ERROR re-analyzing match %d: %v", id, err)
+			status.Failed++
+		} else {
+			status.Updated++
+		}
+		status.Done++
+		status.Last = result
+
+		if progress != nil {
+			progress(status)
+		}
+	}
+
+	return status, ctx.Err()
+}
+
+// checkReplayRounds reports whether the rounds read from a match's replay
+// are the ones in the library
+func checkReplayRounds(match *models.Match, rounds []models.Round, readers []*dissect.Reader) error {
+	if len(readers) != len(rounds) {
+		return fmt.Errorf("%s has %d rounds, the library has %d", match.FilePath, len(readers), len(rounds))
+	}
+	if id := readers[0].Header.MatchID; id != match.MatchID {
+		return fmt.Errorf("%s now holds match %s, not %s", match.FilePath, id, match.MatchID)
+	}
+	return nil
+}
+
+// storedRounds restores a match's rounds from the round_raw table. It fails
+// unless every round was stored.
+func (p *Parser) storedRounds(matchID int64, rounds []models.Round) ([]*dissect.Reader, error) {
+	if len(rounds) == 0 {
+		return nil, fmt.Errorf("the library has no rounds for this match")
+	}
+	raw, err := p.db.GetRoundRaw(matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	readers := make([]*dissect.Reader, 0, len(rounds))
+	for _, round := range rounds {
+		data, ok := raw[round.ID]
+		if !ok {
+			return nil, fmt.Errorf("round %d was imported without its raw data", round.RoundNumber)
+		}
+		reader, err := decodeRound(data)
+		if err != nil {
+			return nil, fmt.Errorf("round %d: %w", round.RoundNumber, err)
+		}
+		readers = append(readers, reader)
+	}
+	return readers, nil
+}
+
+// readReplay decodes every round of a match folder, or a single .rec file,
+// in round order
+func readReplay(ctx context.Context, path string) ([]*dissect.Reader, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	recFiles := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		recFiles = nil
+		for _, entry := range entries {
+			if !entry.IsDir() && isRecFile(entry.Name()) {
+				recFiles = append(recFiles, filepath.Join(path, entry.Name()))
+			}
+		}
+		sort.Strings(recFiles)
+	}
+	if len(recFiles) == 0 {
+		return nil, fmt.Errorf("no .rec files in %s", path)
+	}
+
+	var readers []*dissect.Reader
+	for i, recPath := range recFiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		reader, err := readRecFile(recPath)
+		if err != nil {
+			return nil, fmt.Errorf("round %d (%s): %w", i+1, filepath.Base(recPath), err)
+		}
+		readers = append(readers, reader)
+	}
+	return readers, nil
+}
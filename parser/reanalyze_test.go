@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"r6-replay-recorder/database"
+	"r6-replay-recorder/models"
+)
+
+func TestReanalyzeWithoutReplay(t *testing.T) {
+	tests := []struct {
+		name    string
+		withRaw bool // the round was stored at import
+	}{
+		{"from the rounds stored at import", true},
+		{"without stored rounds", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := database.Open(filepath.Join(t.TempDir(), "replays.db"))
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer db.Close()
+
+			// testRound as a one-round match without stats, whose replay
+			// folder no longer exists
+			reader := testRound()
+			w, err := db.BeginMatch()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Rollback()
+			matchID, err := w.InsertMatch(&models.Match{
+				MatchID: reader.Header.MatchID, Timestamp: reader.Header.Timestamp, Map: "Bank",
+				RoundsPlayed: 1, FilePath: filepath.Join(t.TempDir(), "deleted"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			roundID, err := w.InsertRound(&models.Round{
+				MatchID: matchID, RoundNumber: 1, TeamRole: "Attack", WinCondition: "KilledOpponents",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.withRaw {
+				data, err := encodeRound(reader)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := w.InsertRoundRaw(roundID, matchID, data); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Commit(); err != nil {
+				t.Fatal(err)
+			}
+
+			err = New(db).Reanalyze(context.Background(), matchID)
+			if !tt.withRaw {
+				if err == nil {
+					t.Fatal("Reanalyze succeeded without a replay or stored rounds")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Reanalyze: %v", err)
+			}
+
+			stats, err := db.GetPlayerRoundStatsByMatch(matchID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(stats) != 3 {
+				t.Fatalf("got stats for %d players, want 3", len(stats))
+			}
+			for _, s := range stats {
+				if s.AnalysisVersion != AnalysisVersion {
+					t.Errorf("%s has analysis version %d, want %d", s.Username, s.AnalysisVersion, AnalysisVersion)
+				}
+				if s.Username == "b1" && (s.Kills != 2 || s.Headshots != 1) {
+					t.Errorf("b1 has %d kills and %d headshots, want 2 and 1", s.Kills, s.Headshots)
+				}
+			}
+
+			outdated, err := db.GetOutdatedMatchIDs(AnalysisVersion)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(outdated) != 0 {
+				t.Errorf("matches still outdated after re-analysis: %v", outdated)
+			}
+		})
+	}
+}
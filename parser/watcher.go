@@ -0,0 +1,226 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"r6-replay-recorder/models"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultQuietPeriod is how long a match folder must go without changes
+// before the watcher imports it. The game writes each round's .rec file as
+// the round is played, so it has to outlast the end-of-round screens and
+// operator selection or a match would be imported after its first round.
+// A match imported during a longer pause is replaced once more rounds land.
+const DefaultQuietPeriod = 90 * time.Second
+
+// FolderWatcher imports new matches as they appear under a replay folder.
+// It listens for filesystem events and falls back to polling when events
+// are unavailable (or Poll is set, e.g. for network drives that don't
+// deliver them).
+type FolderWatcher struct {
+	// Poll rescans the whole tree every interval instead of using filesystem events
+	Poll bool
+	// Quiet is how long a folder must be unchanged before it is imported
+	Quiet time.Duration
+
+	path     string
+	parser   *Parser
+	cancel   context.CancelFunc
+	interval time.Duration
+}
+
+// NewFolderWatcher creates a new folder watcher. interval is only used when polling.
+func NewFolderWatcher(path string, parser *Parser, interval time.Duration) *FolderWatcher {
+	return &FolderWatcher{
+		Quiet:    DefaultQuietPeriod,
+		path:     path,
+		parser:   parser,
+		interval: interval,
+	}
+}
+
+// Start begins watching for new replays until ctx is cancelled or Stop is called
+func (fw *FolderWatcher) Start(ctx context.Context, onNewMatch func(*models.Match)) {
+	ctx, fw.cancel = context.WithCancel(ctx)
+
+	if !fw.Poll {
+		notifier, err := fw.newNotifier()
+		if err == nil {
+			go fw.watchEvents(ctx, notifier, onNewMatch)
+			return
+		}
+		log.Printf("WARNING: Filesystem events unavailable for %s, polling every %s instead: %v", fw.path, fw.interval, err)
+	}
+
+	go fw.poll(ctx, onNewMatch)
+}
+
+// Stop stops watching. An import in progress is rolled back.
+func (fw *FolderWatcher) Stop() {
+	if fw.cancel != nil {
+		fw.cancel()
+	}
+}
+
+// newNotifier watches the replay folder and every directory below it, since
+// fsnotify does not watch recursively
+func (fw *FolderWatcher) newNotifier() (*fsnotify.Watcher, error) {
+	notifier, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := addTree(notifier, fw.path); err != nil {
+		notifier.Close()
+		return nil, err
+	}
+	return notifier, nil
+}
+
+func addTree(notifier *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			// The root must be watchable; anything below it is best effort
+			if path == root {
+				return err
+			}
+			log.Printf("ERROR walking path %s: %v", path, err)
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		return notifier.Add(path)
+	})
+}
+
+// watchEvents collects changed match folders from filesystem events and
+// imports each one once it has been quiet for fw.Quiet. Several events for
+// the same folder only push its deadline back, so a match is imported once.
+func (fw *FolderWatcher) watchEvents(ctx context.Context, notifier *fsnotify.Watcher, onNewMatch func(*models.Match)) {
+	defer notifier.Close()
+
+	// Folder -> time of its last change
+	pending := make(map[string]time.Time)
+
+	// Catch up on matches recorded while nothing was watching
+	fw.markAll(ctx, pending)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-notifier.Events:
+			if !ok {
+				return
+			}
+
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := addTree(notifier, event.Name); err != nil {
+						log.Printf("ERROR watching %s: %v", event.Name, err)
+					}
+					// Files may have landed before the watch was added
+					if found, _ := containsRecFiles(event.Name); found {
+						pending[event.Name] = time.Now()
+					}
+					continue
+				}
+			}
+
+			if isRecFile(event.Name) {
+				if folder := filepath.Dir(event.Name); folder != fw.path {
+					pending[folder] = time.Now()
+				}
+			}
+
+		case err, ok := <-notifier.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("ERROR watching %s: %v", fw.path, err)
+			// Events were dropped; find out what changed the slow way
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				fw.markAll(ctx, pending)
+			}
+
+		case now := <-ticker.C:
+			for folder, changed := range pending {
+				if now.Sub(changed) < fw.Quiet {
+					continue
+				}
+				delete(pending, folder)
+				fw.importFolder(ctx, folder, onNewMatch)
+			}
+		}
+	}
+}
+
+// markAll queues every match folder under the replay folder that changed
+// since it was last imported
+func (fw *FolderWatcher) markAll(ctx context.Context, pending map[string]time.Time) {
+	folders, _, err := fw.parser.FindPendingReplayFolders(ctx, fw.path)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, folder := range folders {
+		if _, queued := pending[folder]; !queued {
+			pending[folder] = now
+		}
+	}
+}
+
+// poll rescans the whole tree every interval, skipping folders that are
+// unchanged since their last import or were modified within the quiet period
+func (fw *FolderWatcher) poll(ctx context.Context, onNewMatch func(*models.Match)) {
+	ticker := time.NewTicker(fw.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			folders, _, err := fw.parser.FindPendingReplayFolders(ctx, fw.path)
+			if err != nil {
+				continue
+			}
+
+			for _, folder := range folders {
+				state := replayState(folder)
+				if state == nil || time.Since(state.LatestMtime) < fw.Quiet {
+					continue
+				}
+				fw.importFolder(ctx, folder, onNewMatch)
+			}
+		}
+	}
+}
+
+func (fw *FolderWatcher) importFolder(ctx context.Context, folder string, onNewMatch func(*models.Match)) {
+	// Events that didn't change the .rec files, or a failed folder that
+	// hasn't been touched, are not worth decoding again
+	if fw.parser.unchanged(folder) {
+		return
+	}
+
+	match, err := fw.parser.ImportMatch(ctx, folder)
+	if err != nil {
+		log.Printf("ERROR auto-importing %s: %v", folder, err)
+		return
+	}
+	if match != nil {
+		onNewMatch(match)
+	}
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"r6-replay-recorder/database"
+	"r6-replay-recorder/models"
+)
+
+// openingDuelGroupings are the opening duel card's groupings, in menu order
+var openingDuelGroupings = []struct {
+	label string
+	by    string
+}{
+	{"Player", database.OpeningDuelsByPlayer},
+	{"Operator", database.OpeningDuelsByOperator},
+	{"Side", database.OpeningDuelsBySide},
+	{"Map", database.OpeningDuelsByMap},
+	{"Site", database.OpeningDuelsBySite},
+}
+
+// buildOpeningDuelCard returns your team's opening duels won and lost and
+// the round win rate after each, for the selected stats side. Its grouping
+// and map filter are kept across stats refreshes.
+func (u *UI) buildOpeningDuelCard() fyne.CanvasObject {
+	table := container.NewVBox()
+	refresh := func() {
+		table.Objects = []fyne.CanvasObject{u.buildOpeningDuelTable()}
+		table.Refresh()
+	}
+
+	var labels []string
+	for _, g := range openingDuelGroupings {
+		labels = append(labels, g.label)
+	}
+	bySelect := widget.NewSelect(labels, nil)
+	bySelect.SetSelected(labels[0])
+	for _, g := range openingDuelGroupings {
+		if g.by == u.openingDuelBy {
+			bySelect.SetSelected(g.label)
+		}
+	}
+	bySelect.OnChanged = func(s string) {
+		for _, g := range openingDuelGroupings {
+			if g.label == s {
+				u.openingDuelBy = g.by
+			}
+		}
+		refresh()
+	}
+
+	maps, _ := u.db.GetDistinctMaps()
+	mapSelect := widget.NewSelect(append([]string{"All"}, maps...), nil)
+	mapSelect.SetSelected(selectedOrAll(u.openingDuelMap))
+	mapSelect.OnChanged = func(s string) {
+		u.openingDuelMap = s
+		refresh()
+	}
+
+	refresh()
+	filters := container.NewHBox(
+		widget.NewLabel("By:"), bySelect,
+		widget.NewLabel("Map:"), mapSelect,
+	)
+	return widget.NewCard("Opening Duels", "The first kill of each round, won or lost by your team",
+		container.NewVBox(filters, table))
+}
+
+func (u *UI) buildOpeningDuelTable() fyne.CanvasObject {
+	by := u.openingDuelBy
+	if by == "" {
+		by = database.OpeningDuelsByPlayer
+	}
+	filter := models.StatsFilter{Role: u.statsSide, Map: u.openingDuelMap}
+	stats, err := u.db.GetOpeningDuelStats(by, filter)
+	if err != nil {
+		return widget.NewLabel(fmt.Sprintf("Error loading opening duels: %v", err))
+	}
+	if len(stats) == 0 {
+		return widget.NewLabel("No opening duels match these filters")
+	}
+
+	header := "Player"
+	for _, g := range openingDuelGroupings {
+		if g.by == by {
+			header = g.label
+		}
+	}
+	rows := []fyne.CanvasObject{
+		container.NewGridWithColumns(6,
+			widget.NewLabelWithStyle(header, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Won - Lost", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("+/-", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Won %", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Round Win % After Win", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("After Loss", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		),
+	}
+
+	rate := func(won, played int, pct float64) string {
+		if played == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.0f%% (%d/%d)", pct, won, played)
+	}
+
+	for _, s := range stats {
+		var name fyne.CanvasObject
+		switch by {
+		case database.OpeningDuelsByPlayer:
+			name = u.playerName(s.Username, s.ProfileID)
+		case database.OpeningDuelsByOperator:
+			name = widget.NewLabel(s.Operator)
+		case database.OpeningDuelsBySide:
+			name = widget.NewLabel(s.Side)
+		case database.OpeningDuelsByMap:
+			name = u.mapName(s.Map)
+		default:
+			site := widget.NewLabel(s.Map + " - " + s.Site)
+			site.Wrapping = fyne.TextWrapWord
+			name = site
+		}
+
+		rows = append(rows, container.NewGridWithColumns(6,
+			name,
+			widget.NewLabelWithStyle(fmt.Sprintf("%d - %d", s.Won, s.Lost), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(fmt.Sprintf("%+d", s.Differential), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(fmt.Sprintf("%.0f%%", s.WinRate), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(rate(s.RoundsWonAfterWin, s.Won, s.RoundWinRateAfterWin), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(rate(s.RoundsWonAfterLoss, s.Lost, s.RoundWinRateAfterLoss), fyne.TextAlignCenter, fyne.TextStyle{}),
+		))
+	}
+	return container.NewVBox(rows...)
+}
@@ -0,0 +1,142 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"r6-replay-recorder/models"
+)
+
+// statsPeriods are the date ranges offered by the stats filters, in days (0 = all time)
+var statsPeriods = []struct {
+	label string
+	days  int
+}{
+	{"All time", 0},
+	{"Last 7 days", 7},
+	{"Last 30 days", 30},
+	{"Last 90 days", 90},
+}
+
+// buildOperatorCard returns the operator statistics card. Its filters only
+// re-query the operator table and are kept across stats refreshes.
+func (u *UI) buildOperatorCard() fyne.CanvasObject {
+	table := container.NewVBox()
+	refresh := func() {
+		table.Objects = []fyne.CanvasObject{u.buildOperatorTable(u.operatorFilter)}
+		table.Refresh()
+	}
+
+	playerEntry := widget.NewEntry()
+	playerEntry.SetPlaceHolder("You")
+	playerEntry.SetText(u.operatorFilter.Player)
+	playerEntry.OnSubmitted = func(s string) {
+		u.operatorFilter.Player = s
+		refresh()
+	}
+
+	roleSelect := widget.NewSelect([]string{"All", "Attack", "Defense"}, nil)
+	roleSelect.SetSelected(selectedOrAll(u.operatorFilter.Role))
+	roleSelect.OnChanged = func(s string) {
+		u.operatorFilter.Role = s
+		refresh()
+	}
+
+	maps, _ := u.db.GetDistinctMaps()
+	mapSelect := widget.NewSelect(append([]string{"All"}, maps...), nil)
+	mapSelect.SetSelected(selectedOrAll(u.operatorFilter.Map))
+	mapSelect.OnChanged = func(s string) {
+		u.operatorFilter.Map = s
+		refresh()
+	}
+
+	typeSelect := widget.NewSelect([]string{"All", "Ranked", "QuickMatch", "Unranked", "Standard"}, nil)
+	typeSelect.SetSelected(selectedOrAll(u.operatorFilter.MatchType))
+	typeSelect.OnChanged = func(s string) {
+		u.operatorFilter.MatchType = s
+		refresh()
+	}
+
+	var periods []string
+	for _, p := range statsPeriods {
+		periods = append(periods, p.label)
+	}
+	periodSelect := widget.NewSelect(periods, nil)
+	if u.operatorPeriod == "" {
+		u.operatorPeriod = periods[0]
+	}
+	periodSelect.SetSelected(u.operatorPeriod)
+	periodSelect.OnChanged = func(s string) {
+		u.operatorPeriod = s
+		u.operatorFilter.From = time.Time{}
+		for _, p := range statsPeriods {
+			if p.label == s && p.days > 0 {
+				u.operatorFilter.From = time.Now().AddDate(0, 0, -p.days)
+			}
+		}
+		refresh()
+	}
+
+	filters := container.NewHBox(
+		widget.NewLabel("Player:"), container.NewGridWrap(fyne.NewSize(140, playerEntry.MinSize().Height), playerEntry),
+		widget.NewLabel("Side:"), roleSelect,
+		widget.NewLabel("Map:"), mapSelect,
+		widget.NewLabel("Type:"), typeSelect,
+		periodSelect,
+	)
+
+	refresh()
+	return widget.NewCard("Operator Statistics", "", container.NewVBox(filters, table))
+}
+
+func (u *UI) buildOperatorTable(filter models.StatsFilter) fyne.CanvasObject {
+	stats, err := u.db.GetOperatorStats(filter)
+	if err != nil {
+		return widget.NewLabel(fmt.Sprintf("Error loading operator stats: %v", err))
+	}
+	if len(stats) == 0 {
+		return widget.NewLabel("No rounds match these filters")
+	}
+
+	rows := []fyne.CanvasObject{
+		container.NewGridWithColumns(7,
+			widget.NewLabelWithStyle("Operator", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Picks", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("K", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("D", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("K/D", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Round Win %", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Entry %", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		),
+	}
+
+	for _, stat := range stats {
+		entry := "-"
+		if stat.EntryKills+stat.EntryDeaths > 0 {
+			entry = fmt.Sprintf("%.0f%% (%d/%d)", stat.EntrySuccess, stat.EntryKills, stat.EntryKills+stat.EntryDeaths)
+		}
+		rows = append(rows, container.NewGridWithColumns(7,
+			widget.NewLabel(stat.Operator),
+			widget.NewLabelWithStyle(fmt.Sprintf("%d", stat.TimesUsed), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(fmt.Sprintf("%d", stat.Kills), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(fmt.Sprintf("%d", stat.Deaths), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(fmt.Sprintf("%.2f", stat.KD), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(fmt.Sprintf("%.1f%%", stat.WinRate), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(entry, fyne.TextAlignCenter, fyne.TextStyle{}),
+		))
+	}
+
+	return container.NewVBox(rows...)
+}
+
+// selectedOrAll maps an unset filter field to the "All" option
+func selectedOrAll(v string) string {
+	if v == "" {
+		return "All"
+	}
+	return v
+}
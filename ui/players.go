@@ -0,0 +1,181 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// recentPlayerMatches is how many matches a player profile lists
+const recentPlayerMatches = 20
+
+func (u *UI) buildPlayersTab() fyne.CanvasObject {
+	search := widget.NewEntry()
+	search.SetPlaceHolder("Search players...")
+	search.OnChanged = func(s string) {
+		u.playerSearch = s
+		u.filterPlayers()
+	}
+
+	u.playerList = widget.NewList(
+		func() int {
+			return len(u.shownPlayers)
+		},
+		func() fyne.CanvasObject {
+			return container.NewHBox(
+				widget.NewLabel("Player Name Here"),
+				layout.NewSpacer(),
+				widget.NewLabel("000 matches"),
+				widget.NewLabel("K/D 0.00"),
+			)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= len(u.shownPlayers) {
+				return
+			}
+			p := u.shownPlayers[id]
+			box := obj.(*fyne.Container)
+
+			box.Objects[0].(*widget.Label).SetText(p.Username)
+			box.Objects[2].(*widget.Label).SetText(fmt.Sprintf("%d matches", p.MatchesPlayed))
+			box.Objects[3].(*widget.Label).SetText(fmt.Sprintf("K/D %.2f", p.KD))
+		},
+	)
+
+	u.playerList.OnSelected = func(id widget.ListItemID) {
+		if id < len(u.shownPlayers) {
+			p := u.shownPlayers[id]
+			u.playerList.UnselectAll()
+			u.showPlayerProfile(p.ProfileID)
+		}
+	}
+
+	u.refreshPlayers()
+
+	return container.NewBorder(search, nil, nil, nil, u.playerList)
+}
+
+func (u *UI) refreshPlayers() {
+	if u.playerList == nil {
+		return
+	}
+	players, err := u.db.GetPlayers()
+	if err != nil {
+		return
+	}
+	u.players = players
+	u.filterPlayers()
+}
+
+// filterPlayers applies the search box to the players list
+func (u *UI) filterPlayers() {
+	query := strings.ToLower(strings.TrimSpace(u.playerSearch))
+	u.shownPlayers = u.players
+	if query != "" {
+		u.shownPlayers = nil
+		for _, p := range u.players {
+			if strings.Contains(strings.ToLower(p.Username), query) {
+				u.shownPlayers = append(u.shownPlayers, p)
+			}
+		}
+	}
+	u.playerList.Refresh()
+}
+
+// playerName is a username that opens the player's profile when clicked.
+// profileID may be empty, in which case the profile is looked up by name.
+func (u *UI) playerName(username, profileID string) fyne.CanvasObject {
+	btn := widget.NewButton(username, func() {
+		if profileID != "" {
+			u.showPlayerProfile(profileID)
+			return
+		}
+		u.showPlayerByName(username)
+	})
+	btn.Importance = widget.LowImportance
+	btn.Alignment = widget.ButtonAlignLeading
+	return btn
+}
+
+func (u *UI) showPlayerByName(username string) {
+	profileID, err := u.db.GetProfileIDByUsername(username)
+	if err != nil {
+		dialog.ShowError(err, u.window)
+		return
+	}
+	if profileID == "" {
+		dialog.ShowInformation("Player", fmt.Sprintf("No profile is recorded for %s.", username), u.window)
+		return
+	}
+	u.showPlayerProfile(profileID)
+}
+
+func (u *UI) showPlayerProfile(profileID string) {
+	stats, err := u.db.GetPlayerStats(profileID)
+	if err != nil {
+		dialog.ShowError(err, u.window)
+		return
+	}
+	if stats == nil {
+		dialog.ShowInformation("Player", "This player has no recorded rounds.", u.window)
+		return
+	}
+	matches, _ := u.db.GetPlayerMatches(profileID, recentPlayerMatches)
+
+	stat := func(label, value string) fyne.CanvasObject {
+		return container.NewVBox(
+			widget.NewLabelWithStyle(label, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle(value, fyne.TextAlignCenter, fyne.TextStyle{}),
+		)
+	}
+
+	content := container.NewVBox(
+		widget.NewLabelWithStyle(stats.Username, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+	)
+	if len(stats.Aliases) > 1 {
+		var names []string
+		for _, a := range stats.Aliases[1:] {
+			names = append(names, fmt.Sprintf("%s (%s - %s)", a.Username,
+				a.FirstSeen.Local().Format("2006-01-02"), a.LastSeen.Local().Format("2006-01-02")))
+		}
+		aliases := widget.NewLabel("Also known as: " + strings.Join(names, ", "))
+		aliases.Wrapping = fyne.TextWrapWord
+		content.Add(aliases)
+	}
+	content.Add(container.NewGridWithColumns(5,
+		stat("Rating", fmt.Sprintf("%.2f", stats.Rating)),
+		stat("Matches", fmt.Sprintf("%d", stats.MatchesPlayed)),
+		stat("Rounds", fmt.Sprintf("%d", stats.RoundsPlayed)),
+		stat("Win Rate", fmt.Sprintf("%.1f%%", stats.WinRate)),
+		stat("K/D", fmt.Sprintf("%.2f (%d/%d)", stats.KD, stats.Kills, stats.Deaths)),
+		stat("HS%", fmt.Sprintf("%.1f%%", stats.HeadshotPercentage)),
+		stat("Entry +/-", fmt.Sprintf("%+d (%d/%d)", stats.EntryDifferential, stats.EntryKills, stats.EntryDeaths)),
+		stat("KOST", fmt.Sprintf("%.1f%%", stats.KOST)),
+		stat("Clutch", fmt.Sprintf("%.1f%% (%d/%d)", stats.ClutchRate, stats.ClutchWins, stats.ClutchAttempts)),
+		stat("Assists", fmt.Sprintf("%d", stats.Assists)),
+	))
+	content.Add(widget.NewSeparator())
+	content.Add(widget.NewLabelWithStyle("Recent Matches:", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+
+	for _, pm := range matches {
+		m := pm.Match
+		text := fmt.Sprintf("%s  %s - %s  %d/%d/%d  %.2f  %s",
+			m.Timestamp.Format("2006-01-02"), m.Map, m.MatchType,
+			pm.Kills, pm.Deaths, pm.Assists, pm.Rating, boolToResult(pm.Won))
+		content.Add(widget.NewButton(text, func() {
+			u.showMatchDetails(m)
+		}))
+	}
+
+	scroll := container.NewVScroll(content)
+	scroll.SetMinSize(fyne.NewSize(600, 500))
+
+	d := dialog.NewCustom("Player Profile", "Close", scroll, u.window)
+	d.Resize(fyne.NewSize(650, 550))
+	d.Show()
+}
@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// mapName is a map name that opens its site breakdown when clicked
+func (u *UI) mapName(name string) fyne.CanvasObject {
+	btn := widget.NewButton(name, func() {
+		u.showMapSites(name)
+	})
+	btn.Importance = widget.LowImportance
+	btn.Alignment = widget.ButtonAlignLeading
+	return btn
+}
+
+// showMapSites shows attack and defense win rates, plant success and win
+// conditions for each site of a map
+func (u *UI) showMapSites(name string) {
+	stats, err := u.db.GetSiteStats(name)
+	if err != nil {
+		dialog.ShowError(err, u.window)
+		return
+	}
+	if len(stats) == 0 {
+		dialog.ShowInformation(name, "No rounds with a known site on this map.", u.window)
+		return
+	}
+
+	rows := []fyne.CanvasObject{
+		container.NewGridWithColumns(6,
+			widget.NewLabelWithStyle("Site", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Rounds", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Attack Win %", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Defense Win %", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Plant Success", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Win Conditions", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		),
+	}
+
+	rate := func(won, played int, pct float64) string {
+		if played == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.0f%% (%d/%d)", pct, won, played)
+	}
+
+	for _, s := range stats {
+		var conditions []string
+		for _, wc := range s.WinConditions {
+			conditions = append(conditions, fmt.Sprintf("%s %d", winConditionLabel(wc.WinCondition), wc.Rounds))
+		}
+		site := widget.NewLabel(s.Site)
+		site.Wrapping = fyne.TextWrapWord
+		wins := widget.NewLabel(strings.Join(conditions, "\n"))
+
+		rows = append(rows, container.NewGridWithColumns(6,
+			site,
+			widget.NewLabelWithStyle(fmt.Sprintf("%d", s.Rounds), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(rate(s.AttackWins, s.AttackRounds, s.AttackWinRate), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(rate(s.DefenseWins, s.DefenseRounds, s.DefenseWinRate), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(rate(s.PlantsWon, s.Plants, s.PlantSuccess), fyne.TextAlignCenter, fyne.TextStyle{}),
+			wins,
+		))
+		rows = append(rows, widget.NewSeparator())
+	}
+
+	content := container.NewVBox(
+		widget.NewLabelWithStyle(name+" Sites", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		widget.NewLabel("Win rates are from your team's side. Plant success is how often a planted defuser won the round for the attackers."),
+	)
+	content.Add(container.NewVBox(rows...))
+
+	scroll := container.NewVScroll(content)
+	scroll.SetMinSize(fyne.NewSize(800, 500))
+
+	d := dialog.NewCustom("Site Statistics", "Close", scroll, u.window)
+	d.Resize(fyne.NewSize(850, 550))
+	d.Show()
+}
@@ -0,0 +1,285 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"r6-replay-recorder/analysis"
+	"r6-replay-recorder/models"
+)
+
+// timelineLanes is how many rows of markers the time axis has. Markers too
+// close to fit in any row are drawn over the least recently used one.
+const timelineLanes = 4
+
+// timelineIcons are the marker icons per event type
+var timelineIcons = map[string]fyne.Resource{
+	"Kill":                   theme.ContentClearIcon(),
+	"Death":                  theme.CancelIcon(),
+	"DefuserPlantStart":      theme.MediaPlayIcon(),
+	"DefuserPlantComplete":   theme.WarningIcon(),
+	"DefuserDisableStart":    theme.MediaPauseIcon(),
+	"DefuserDisableComplete": theme.ConfirmIcon(),
+	"LocateObjective":        theme.SearchIcon(),
+	"OperatorSwap":           theme.AccountIcon(),
+	"PlayerLeave":            theme.LogoutIcon(),
+	"Battleye":               theme.ErrorIcon(),
+}
+
+func timelineIcon(eventType string) fyne.Resource {
+	if icon, ok := timelineIcons[eventType]; ok {
+		return icon
+	}
+	return theme.InfoIcon()
+}
+
+// timelineEventText describes an event for the event list and tooltips
+func timelineEventText(e models.MatchEvent) string {
+	switch e.EventType {
+	case "Kill":
+		if e.Headshot {
+			return fmt.Sprintf("%s killed %s (headshot)", e.Username, e.Target)
+		}
+		return fmt.Sprintf("%s killed %s", e.Username, e.Target)
+	case "Death":
+		return e.Username + " died"
+	case "DefuserPlantStart":
+		return e.Username + " started planting the defuser"
+	case "DefuserPlantComplete":
+		return e.Username + " planted the defuser"
+	case "DefuserDisableStart":
+		return e.Username + " started disabling the defuser"
+	case "DefuserDisableComplete":
+		return e.Username + " disabled the defuser"
+	case "LocateObjective":
+		return e.Username + " located the objective"
+	case "OperatorSwap":
+		return e.Username + " swapped operators"
+	case "PlayerLeave":
+		return e.Username + " left the match"
+	}
+
+	text := e.EventType
+	if e.Username != "" {
+		text += ": " + e.Username
+	}
+	if e.Message != "" {
+		text += " - " + e.Message
+	}
+	return text
+}
+
+// formatElapsed formats seconds since the action phase started as m:ss
+func formatElapsed(seconds float64) string {
+	s := int(seconds)
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// buildRoundTimeline returns a round's events on a time axis, followed by
+// the same events as a list with the players left alive after each. Teams
+// are taken from the round's player stats.
+func (u *UI) buildRoundTimeline(events []models.MatchEvent, playerStats []models.PlayerRoundStats, round models.Round, match models.Match) fyne.CanvasObject {
+	teams := make(map[string]int, len(playerStats))
+	for _, s := range playerStats {
+		teams[s.Username] = s.TeamIndex
+	}
+
+	// Feed entries of type Other carry nothing to show unless named
+	var shown []models.MatchEvent
+	var converted []analysis.Event
+	for _, e := range events {
+		if e.EventType == "Other" && e.Username == "" && e.Message == "" {
+			continue
+		}
+		shown = append(shown, e)
+		converted = append(converted, analysis.Event{
+			Type:     e.EventType,
+			Time:     float64(e.TimeInSeconds),
+			Username: e.Username,
+			Target:   e.Target,
+		})
+	}
+	if len(shown) == 0 {
+		return widget.NewLabel("No events recorded for this round")
+	}
+
+	moments, length := analysis.RoundTimeline(converted, teams, round.WinCondition)
+	if length <= 0 {
+		length = analysis.ActionPhaseSeconds
+	}
+
+	// Alive counts read as your team v opponents
+	yours := match.RecordingTeamIndex
+	if yours != 0 && yours != 1 {
+		yours = 0
+	}
+	alive := func(m analysis.Moment) string {
+		return fmt.Sprintf("%dv%d", m.Alive[yours], m.Alive[1-yours])
+	}
+	start := analysis.Moment{}
+	for _, team := range teams {
+		if team == 0 || team == 1 {
+			start.Alive[team]++
+		}
+	}
+
+	markerColor := func(e models.MatchEvent) color.Color {
+		team, ok := teams[e.Username]
+		switch {
+		case !ok:
+			return theme.DisabledColor()
+		case team == yours:
+			return theme.SuccessColor()
+		}
+		return theme.ErrorColor()
+	}
+
+	markers := make([]fyne.CanvasObject, len(shown))
+	times := make([]float64, len(shown))
+	rows := []fyne.CanvasObject{
+		container.NewGridWithColumns(3,
+			widget.NewLabelWithStyle("Time (Clock)", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Event", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Alive", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		),
+	}
+	before := start
+	for i, e := range shown {
+		m := moments[i]
+		text := timelineEventText(e)
+		change := alive(m)
+		if m.Alive != before.Alive {
+			change = alive(before) + " → " + alive(m)
+		}
+		before = m
+
+		tooltip := fmt.Sprintf("%s (clock %s)\n%s\nAlive %s", formatElapsed(m.Time), e.Time, text, change)
+		markers[i] = newTimelineMarker(timelineIcon(e.EventType), markerColor(e), tooltip)
+		times[i] = m.Time
+
+		event := widget.NewLabel(text)
+		event.Wrapping = fyne.TextWrapWord
+		rows = append(rows, container.NewGridWithColumns(3,
+			widget.NewLabel(fmt.Sprintf("%s (%s)", formatElapsed(m.Time), e.Time)),
+			container.NewBorder(nil, nil, widget.NewIcon(timelineIcon(e.EventType)), nil, event),
+			widget.NewLabelWithStyle(change, fyne.TextAlignCenter, fyne.TextStyle{}),
+		))
+	}
+
+	// Axis ticks every 30 seconds, and the end of the round unless a tick is
+	// close enough to overlap it
+	var ticks []fyne.CanvasObject
+	var tickTimes []float64
+	for t := 0.0; t+15 < length; t += 30 {
+		ticks = append(ticks, widget.NewLabel(formatElapsed(t)))
+		tickTimes = append(tickTimes, t)
+	}
+	ticks = append(ticks, widget.NewLabel(formatElapsed(length)))
+	tickTimes = append(tickTimes, length)
+
+	axis := canvas.NewRectangle(theme.ForegroundColor())
+	axis.SetMinSize(fyne.NewSize(0, 2))
+
+	return container.NewVBox(
+		container.New(&timelineLayout{times: times, length: length, lanes: timelineLanes}, markers...),
+		axis,
+		container.New(&timelineLayout{times: tickTimes, length: length, lanes: 1}, ticks...),
+		widget.NewLabel("Markers are green for your team and red for opponents. Hover a marker for details."),
+		widget.NewSeparator(),
+		container.NewVBox(rows...),
+	)
+}
+
+// timelineLayout places each object along the width at its time, stacking
+// objects that would overlap into lanes
+type timelineLayout struct {
+	times  []float64
+	length float64
+	lanes  int
+}
+
+func (l *timelineLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	var size fyne.Size
+	for _, o := range objects {
+		size = size.Max(o.MinSize())
+	}
+	return fyne.NewSize(size.Width, size.Height*float32(l.lanes))
+}
+
+func (l *timelineLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	ends := make([]float32, l.lanes) // right edge of the last object in each lane
+	for i, o := range objects {
+		min := o.MinSize()
+		x := (size.Width - min.Width) * float32(l.times[i]/l.length)
+
+		// The first lane with room, or else the one freed up longest ago
+		lane := 0
+		for j := range ends {
+			if ends[j] <= x {
+				lane = j
+				break
+			}
+			if ends[j] < ends[lane] {
+				lane = j
+			}
+		}
+		ends[lane] = x + min.Width
+
+		o.Resize(min)
+		o.Move(fyne.NewPos(x, min.Height*float32(lane)))
+	}
+}
+
+// timelineMarker is an event icon on a coloured dot that shows its tooltip
+// while hovered
+type timelineMarker struct {
+	widget.BaseWidget
+	icon    fyne.Resource
+	color   color.Color
+	tooltip string
+	popUp   *widget.PopUp
+}
+
+func newTimelineMarker(icon fyne.Resource, c color.Color, tooltip string) *timelineMarker {
+	m := &timelineMarker{icon: icon, color: c, tooltip: tooltip}
+	m.ExtendBaseWidget(m)
+	return m
+}
+
+func (m *timelineMarker) CreateRenderer() fyne.WidgetRenderer {
+	dot := canvas.NewCircle(m.color)
+	icon := widget.NewIcon(m.icon)
+	return widget.NewSimpleRenderer(container.NewStack(dot, container.NewPadded(icon)))
+}
+
+func (m *timelineMarker) MinSize() fyne.Size {
+	return fyne.NewSquareSize(theme.IconInlineSize() + theme.Padding()*2)
+}
+
+// MouseIn shows the tooltip just below and right of the pointer, so the
+// pop-up doesn't take the hover from the marker
+func (m *timelineMarker) MouseIn(e *desktop.MouseEvent) {
+	c := fyne.CurrentApp().Driver().CanvasForObject(m)
+	if c == nil {
+		return
+	}
+	if m.popUp == nil {
+		m.popUp = widget.NewPopUp(widget.NewLabel(m.tooltip), c)
+	}
+	m.popUp.ShowAtPosition(e.AbsolutePosition.Add(fyne.NewPos(theme.Padding()*2, theme.Padding()*2)))
+}
+
+func (m *timelineMarker) MouseMoved(*desktop.MouseEvent) {}
+
+func (m *timelineMarker) MouseOut() {
+	if m.popUp != nil {
+		m.popUp.Hide()
+	}
+}
@@ -1,9 +1,13 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -27,13 +31,31 @@ type UI struct {
 	matches   []models.Match
 	matchList *widget.List
 
+	// Players tab; shownPlayers is players narrowed by the search box
+	players      []models.PlayerStats
+	shownPlayers []models.PlayerStats
+	playerSearch string
+	playerList   *widget.List
+
 	// Filter widgets
 	mapFilter  *widget.Select
 	typeFilter *widget.Select
 	wonFilter  *widget.Select
 
-	// Stats labels
+	// Stats labels; statsSide is "Attack", "Defense" or "" for both sides
 	statsContainer *fyne.Container
+	statsSide      string
+
+	// Operator stats filters, kept across stats refreshes
+	operatorFilter models.StatsFilter
+	operatorPeriod string
+
+	// Win condition card map filter
+	winConditionMap string
+
+	// Opening duel card grouping and map filter
+	openingDuelBy  string
+	openingDuelMap string
 
 	// Track if UI is fully initialized
 	initialized bool
@@ -55,6 +77,7 @@ func (u *UI) Build() fyne.CanvasObject {
 	tabs := container.NewAppTabs(
 		container.NewTabItem("Matches", u.buildMatchesTab()),
 		container.NewTabItem("Stats", u.buildStatsTab()),
+		container.NewTabItem("Players", u.buildPlayersTab()),
 		container.NewTabItem("Settings", u.buildSettingsTab()),
 	)
 	tabs.SetTabLocation(container.TabLocationTop)
@@ -159,9 +182,18 @@ func (u *UI) buildMatchesTab() fyne.CanvasObject {
 
 func (u *UI) buildStatsTab() fyne.CanvasObject {
 	u.statsContainer = container.NewVBox()
-	u.updateStats()
 
-	return container.NewVScroll(u.statsContainer)
+	sideSelect := widget.NewSelect([]string{"Both Sides", "Attack", "Defense"}, func(s string) {
+		u.statsSide = ""
+		if s != "Both Sides" {
+			u.statsSide = s
+		}
+		u.updateStats()
+	})
+	sideSelect.SetSelected("Both Sides")
+
+	header := container.NewHBox(widget.NewLabel("Side:"), sideSelect)
+	return container.NewBorder(header, nil, nil, nil, container.NewVScroll(u.statsContainer))
 }
 
 func (u *UI) buildSettingsTab() fyne.CanvasObject {
@@ -203,14 +235,55 @@ func (u *UI) buildSettingsTab() fyne.CanvasObject {
 	})
 	autoImport.Checked = settings.AutoImport
 
+	// Network drives don't report file changes, so the watcher can poll instead
+	watchPolling := widget.NewCheck("Poll for changes instead (network drives)", func(checked bool) {
+		settings.WatchPolling = checked
+		u.db.UpdateSettings(settings)
+		if u.watcher != nil && folderEntry.Text != "" {
+			u.StartWatcher(folderEntry.Text)
+		}
+	})
+	watchPolling.Checked = settings.WatchPolling
+
+	// Analysis windows; unset or invalid values fall back to the defaults
+	cfg := u.parser.AnalysisConfig()
+	tradeEntry := widget.NewEntry()
+	tradeEntry.SetText(strconv.FormatFloat(cfg.TradeWindow, 'f', -1, 64))
+	multiKillEntry := widget.NewEntry()
+	multiKillEntry.SetText(strconv.FormatFloat(cfg.MultiKillWindow, 'f', -1, 64))
+
 	// Save button
 	saveBtn := widget.NewButtonWithIcon("Save Settings", theme.DocumentSaveIcon(), func() {
+		trade, err := strconv.ParseFloat(tradeEntry.Text, 64)
+		if err != nil || trade <= 0 {
+			dialog.ShowError(fmt.Errorf("trade window must be a positive number of seconds"), u.window)
+			return
+		}
+		multiKill, err := strconv.ParseFloat(multiKillEntry.Text, 64)
+		if err != nil || multiKill <= 0 {
+			dialog.ShowError(fmt.Errorf("multi-kill window must be a positive number of seconds"), u.window)
+			return
+		}
+
+		windowsChanged := trade != cfg.TradeWindow || multiKill != cfg.MultiKillWindow
 		settings.ReplayFolder = folderEntry.Text
+		settings.Analysis = models.AnalysisConfig{TradeWindow: trade, MultiKillWindow: multiKill}
 		if err := u.db.UpdateSettings(settings); err != nil {
 			dialog.ShowError(err, u.window)
-		} else {
+			return
+		}
+		cfg = settings.Analysis
+
+		if !windowsChanged {
 			dialog.ShowInformation("Settings", "Settings saved successfully!", u.window)
+			return
 		}
+		// Stored stats keep the windows they were computed with until re-analyzed
+		dialog.ShowConfirm("Settings", "Settings saved. Re-analyze the library now so existing matches use the new windows?", func(ok bool) {
+			if ok {
+				u.showReanalyzeDialog(true)
+			}
+		}, u.window)
 	})
 
 	// Data management
@@ -218,12 +291,16 @@ func (u *UI) buildSettingsTab() fyne.CanvasObject {
 		u.exportData()
 	})
 
+	importDataBtn := widget.NewButtonWithIcon("Import Data (JSON)", theme.UploadIcon(), func() {
+		u.importData()
+	})
+
 	clearBtn := widget.NewButtonWithIcon("Clear All Data", theme.DeleteIcon(), func() {
-		dialog.ShowConfirm("Clear Data", "Are you sure you want to delete all match data? This cannot be undone.", func(ok bool) {
-			if ok {
-				dialog.ShowInformation("Cleared", "All data has been cleared.", u.window)
-			}
-		}, u.window)
+		u.clearData()
+	})
+
+	reanalyzeBtn := widget.NewButtonWithIcon("Re-analyze Library", theme.ViewRefreshIcon(), func() {
+		u.showReanalyzeDialog(false)
 	})
 
 	form := container.NewVBox(
@@ -232,11 +309,19 @@ func (u *UI) buildSettingsTab() fyne.CanvasObject {
 		testBtn,
 		widget.NewSeparator(),
 		autoImport,
+		watchPolling,
+		widget.NewSeparator(),
+		widget.NewLabel("Analysis:"),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Trade window (seconds)"), tradeEntry,
+			widget.NewLabel("Multi-kill window (seconds)"), multiKillEntry,
+		),
 		widget.NewSeparator(),
 		saveBtn,
 		widget.NewSeparator(),
 		widget.NewLabel("Data Management:"),
-		container.NewHBox(exportBtn, clearBtn),
+		container.NewHBox(exportBtn, importDataBtn, clearBtn),
+		reanalyzeBtn,
 	)
 
 	return container.NewPadded(form)
@@ -248,17 +333,24 @@ func (u *UI) testFolderDetection(path string) {
 		return
 	}
 
+	// Closing the dialog (its Cancel button) stops the scan
+	ctx, cancel := context.WithCancel(context.Background())
+
 	// Show progress dialog
 	progressDialog := dialog.NewCustom("Testing Folder Detection", "Cancel",
 		widget.NewLabel("Scanning for replay folders..."), u.window)
+	progressDialog.SetOnClosed(cancel)
 	progressDialog.Show()
 
 	go func() {
-		folders, err := u.parser.FindReplayFolders(path)
+		folders, err := u.parser.FindReplayFolders(ctx, path)
 
 		// Close progress dialog
 		progressDialog.Hide()
 
+		if errors.Is(err, context.Canceled) {
+			return
+		}
 		if err != nil {
 			dialog.ShowError(fmt.Errorf("Error scanning folder: %v", err), u.window)
 			return
@@ -305,6 +397,7 @@ func (u *UI) refreshMatches() {
 		u.matchList.Refresh()
 	}
 	u.updateStats()
+	u.refreshPlayers()
 }
 
 func (u *UI) applyFilters() {
@@ -353,22 +446,30 @@ func (u *UI) updateStats() {
 		return
 	}
 
-	played, wins, losses, winRate, err := u.db.GetOverallStats()
+	played, wins, losses, winRate, err := u.db.GetOverallStats(u.statsSide)
 	if err != nil {
 		return
 	}
 
-	mapStats, _ := u.db.GetMapStats()
-	clutchStats, _ := u.db.GetClutchStats()
-	defuserStats, _ := u.db.GetDefuserStats()
+	mapStats, _ := u.db.GetMapStats(u.statsSide)
+	clutchStats, _ := u.db.GetClutchStats(u.statsSide)
+	defuserStats, _ := u.db.GetDefuserStats(u.statsSide)
+	kostStats, _ := u.db.GetKOSTStats(u.statsSide)
+	ratingStats, _ := u.db.GetRatingStats(u.statsSide)
+
+	// With a side selected, results are counted in rounds instead of matches
+	playedLabel, title := "Matches", "Overall Statistics"
+	if u.statsSide != "" {
+		playedLabel, title = "Rounds", u.statsSide+" Statistics"
+	}
 
 	u.statsContainer.Objects = nil
 
 	// Overall stats card
-	overallCard := widget.NewCard("Overall Statistics", "",
+	overallCard := widget.NewCard(title, "",
 		container.NewGridWithColumns(4,
 			container.NewVBox(
-				widget.NewLabelWithStyle("Matches", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+				widget.NewLabelWithStyle(playedLabel, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 				widget.NewLabelWithStyle(fmt.Sprintf("%d", played), fyne.TextAlignCenter, fyne.TextStyle{}),
 			),
 			container.NewVBox(
@@ -390,30 +491,48 @@ func (u *UI) updateStats() {
 	// Map stats
 	if len(mapStats) > 0 {
 		mapRows := []fyne.CanvasObject{
-			container.NewGridWithColumns(5,
+			container.NewGridWithColumns(7,
 				widget.NewLabelWithStyle("Map", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
-				widget.NewLabelWithStyle("Played", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+				widget.NewLabelWithStyle(playedLabel, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 				widget.NewLabelWithStyle("Wins", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 				widget.NewLabelWithStyle("Losses", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 				widget.NewLabelWithStyle("Win Rate", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+				widget.NewLabelWithStyle("Attack", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+				widget.NewLabelWithStyle("Defense", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 			),
 		}
 
+		sideRate := func(wins, rounds int, rate float64) string {
+			if rounds == 0 {
+				return "-"
+			}
+			return fmt.Sprintf("%.0f%% (%d/%d)", rate, wins, rounds)
+		}
+
 		for _, stat := range mapStats {
-			row := container.NewGridWithColumns(5,
-				widget.NewLabel(stat.MapName),
+			row := container.NewGridWithColumns(7,
+				u.mapName(stat.MapName),
 				widget.NewLabelWithStyle(fmt.Sprintf("%d", stat.Played), fyne.TextAlignCenter, fyne.TextStyle{}),
 				widget.NewLabelWithStyle(fmt.Sprintf("%d", stat.Wins), fyne.TextAlignCenter, fyne.TextStyle{}),
 				widget.NewLabelWithStyle(fmt.Sprintf("%d", stat.Losses), fyne.TextAlignCenter, fyne.TextStyle{}),
 				widget.NewLabelWithStyle(fmt.Sprintf("%.1f%%", stat.WinRate), fyne.TextAlignCenter, fyne.TextStyle{}),
+				widget.NewLabelWithStyle(sideRate(stat.AttackWins, stat.AttackRounds, stat.AttackWinRate), fyne.TextAlignCenter, fyne.TextStyle{}),
+				widget.NewLabelWithStyle(sideRate(stat.DefenseWins, stat.DefenseRounds, stat.DefenseWinRate), fyne.TextAlignCenter, fyne.TextStyle{}),
 			)
 			mapRows = append(mapRows, row)
 		}
 
-		mapCard := widget.NewCard("Map Statistics", "", container.NewVBox(mapRows...))
+		mapCard := widget.NewCard("Map Statistics", "Click a map for its sites", container.NewVBox(mapRows...))
 		u.statsContainer.Add(mapCard)
 	}
 
+	// Operator stats and win conditions
+	if played > 0 {
+		u.statsContainer.Add(u.buildOperatorCard())
+		u.statsContainer.Add(u.buildWinConditionCard())
+		u.statsContainer.Add(u.buildOpeningDuelCard())
+	}
+
 	// Clutch stats
 	if len(clutchStats) > 0 {
 		clutchRows := []fyne.CanvasObject{
@@ -430,7 +549,7 @@ func (u *UI) updateStats() {
 
 		for _, stat := range clutchStats {
 			row := container.NewGridWithColumns(7,
-				widget.NewLabel(stat.Username),
+				u.playerName(stat.Username, stat.ProfileID),
 				widget.NewLabelWithStyle(fmt.Sprintf("%d/%d", stat.Clutch1v1Won, stat.Clutch1v1), fyne.TextAlignCenter, fyne.TextStyle{}),
 				widget.NewLabelWithStyle(fmt.Sprintf("%d/%d", stat.Clutch1v2Won, stat.Clutch1v2), fyne.TextAlignCenter, fyne.TextStyle{}),
 				widget.NewLabelWithStyle(fmt.Sprintf("%d/%d", stat.Clutch1v3Won, stat.Clutch1v3), fyne.TextAlignCenter, fyne.TextStyle{}),
@@ -459,7 +578,7 @@ func (u *UI) updateStats() {
 
 		for _, stat := range defuserStats {
 			row := container.NewGridWithColumns(5,
-				widget.NewLabel(stat.Username),
+				u.playerName(stat.Username, stat.ProfileID),
 				widget.NewLabelWithStyle(fmt.Sprintf("%d", stat.Plants), fyne.TextAlignCenter, fyne.TextStyle{}),
 				widget.NewLabelWithStyle(fmt.Sprintf("%d", stat.Defuses), fyne.TextAlignCenter, fyne.TextStyle{}),
 				widget.NewLabelWithStyle(fmt.Sprintf("%d", stat.PlantDenials), fyne.TextAlignCenter, fyne.TextStyle{}),
@@ -472,6 +591,56 @@ func (u *UI) updateStats() {
 		u.statsContainer.Add(defuserCard)
 	}
 
+	// KOST and survival stats
+	if len(kostStats) > 0 {
+		kostRows := []fyne.CanvasObject{
+			container.NewGridWithColumns(4,
+				widget.NewLabelWithStyle("Player", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+				widget.NewLabelWithStyle("Rounds", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+				widget.NewLabelWithStyle("KOST", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+				widget.NewLabelWithStyle("Avg Survival", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			),
+		}
+
+		for _, stat := range kostStats {
+			row := container.NewGridWithColumns(4,
+				u.playerName(stat.Username, stat.ProfileID),
+				widget.NewLabelWithStyle(fmt.Sprintf("%d", stat.Rounds), fyne.TextAlignCenter, fyne.TextStyle{}),
+				widget.NewLabelWithStyle(fmt.Sprintf("%.1f%%", stat.KOSTRate), fyne.TextAlignCenter, fyne.TextStyle{}),
+				widget.NewLabelWithStyle(fmt.Sprintf("%.0fs", stat.AvgSurvivalTime), fyne.TextAlignCenter, fyne.TextStyle{}),
+			)
+			kostRows = append(kostRows, row)
+		}
+
+		kostCard := widget.NewCard("KOST & Survival", "", container.NewVBox(kostRows...))
+		u.statsContainer.Add(kostCard)
+	}
+
+	// SiegeScope Rating
+	if len(ratingStats) > 0 {
+		ratingRows := []fyne.CanvasObject{
+			container.NewGridWithColumns(4,
+				widget.NewLabelWithStyle("Player", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+				widget.NewLabelWithStyle("Rounds", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+				widget.NewLabelWithStyle("Rating", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+				widget.NewLabelWithStyle("Best Match", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			),
+		}
+
+		for _, stat := range ratingStats {
+			row := container.NewGridWithColumns(4,
+				u.playerName(stat.Username, stat.ProfileID),
+				widget.NewLabelWithStyle(fmt.Sprintf("%d", stat.Rounds), fyne.TextAlignCenter, fyne.TextStyle{}),
+				widget.NewLabelWithStyle(fmt.Sprintf("%.2f", stat.Rating), fyne.TextAlignCenter, fyne.TextStyle{}),
+				widget.NewLabelWithStyle(fmt.Sprintf("%.2f", stat.Best), fyne.TextAlignCenter, fyne.TextStyle{}),
+			)
+			ratingRows = append(ratingRows, row)
+		}
+
+		ratingCard := widget.NewCard("SiegeScope Rating", "Average per round; 1.00 is an average round", container.NewVBox(ratingRows...))
+		u.statsContainer.Add(ratingCard)
+	}
+
 	u.statsContainer.Refresh()
 }
 
@@ -481,15 +650,22 @@ func (u *UI) showImportDialog() {
 			return
 		}
 
+		// Closing the dialog (its Cancel button) rolls the import back
+		ctx, cancel := context.WithCancel(context.Background())
+
 		// Show progress
 		progressDialog := dialog.NewCustom("Importing", "Cancel",
 			widget.NewLabel("Importing match..."), u.window)
+		progressDialog.SetOnClosed(cancel)
 		progressDialog.Show()
 
 		go func() {
-			match, err := u.parser.ImportMatch(uri.Path())
+			match, err := u.parser.ImportMatch(ctx, uri.Path())
 			progressDialog.Hide()
 
+			if errors.Is(err, context.Canceled) {
+				return
+			}
 			if err != nil {
 				dialog.ShowError(err, u.window)
 				return
@@ -515,16 +691,26 @@ func (u *UI) showImportAllDialog() {
 
 		rootPath := uri.Path()
 
+		// Closing the dialog (its Cancel button) stops the import
+		ctx, cancel := context.WithCancel(context.Background())
+
 		// Show progress dialog
 		statusLabel := widget.NewLabel("Scanning for replay folders...")
+		progressBar := widget.NewProgressBar()
 		progressDialog := dialog.NewCustom("Importing All Matches", "Cancel",
-			container.NewVBox(statusLabel), u.window)
+			container.NewVBox(statusLabel, progressBar), u.window)
+		progressDialog.SetOnClosed(cancel)
 		progressDialog.Show()
 
 		go func() {
+			defer cancel()
+
 			// Find folders
-			log.Printf("Starting FindReplayFolders for: %s", rootPath)
-			folders, err := u.parser.FindReplayFolders(rootPath)
+			log.Printf("Starting FindPendingReplayFolders for: %s", rootPath)
+			folders, unchanged, err := u.parser.FindPendingReplayFolders(ctx, rootPath)
+			if errors.Is(err, context.Canceled) {
+				return
+			}
 			if err != nil {
 				progressDialog.Hide()
 				dialog.ShowError(err, u.window)
@@ -533,6 +719,12 @@ func (u *UI) showImportAllDialog() {
 
 			log.Printf("Found %d folders to import", len(folders))
 
+			if len(folders) == 0 && unchanged > 0 {
+				progressDialog.Hide()
+				dialog.ShowInformation("Import", fmt.Sprintf("All %d replay folders are unchanged since they were last imported.", unchanged), u.window)
+				return
+			}
+
 			if len(folders) == 0 {
 				progressDialog.Hide()
 				msg := "No replay folders found.\n\n"
@@ -545,36 +737,35 @@ func (u *UI) showImportAllDialog() {
 			}
 
 			// Import matches
-			imported := 0
-			skipped := 0
-			failed := 0
-
-			for i, folder := range folders {
-				statusLabel.SetText(fmt.Sprintf("Importing match %d of %d...\n%s",
-					i+1, len(folders), filepath.Base(folder)))
-
-				match, err := u.parser.ImportMatch(folder)
-				if err != nil {
-					log.Printf("ERROR importing %s: %v", folder, err)
-					failed++
-				} else if match != nil {
-					imported++
-				} else {
-					skipped++
-				}
-			}
+			statusLabel.SetText(fmt.Sprintf("Importing %d matches...", len(folders)))
+			result, err := u.parser.ImportMany(ctx, folders, 0, func(p parser.ImportProgress) {
+				statusLabel.SetText(fmt.Sprintf("Imported %d of %d...\n%s",
+					p.Done, p.Total, filepath.Base(p.Last.Folder)))
+				progressBar.SetValue(float64(p.Done) / float64(p.Total))
+			})
+			cancelled := errors.Is(err, context.Canceled)
 
 			progressDialog.Hide()
 
 			msg := fmt.Sprintf("Import Complete!\n\n")
-			msg += fmt.Sprintf("✓ Imported: %d new matches\n", imported)
-			if skipped > 0 {
-				msg += fmt.Sprintf("⊘ Skipped: %d (already in database)\n", skipped)
+			if cancelled {
+				msg = fmt.Sprintf("Import Cancelled\n\n")
+			}
+			msg += fmt.Sprintf("✓ Imported: %d new matches\n", result.Imported)
+			if result.Skipped > 0 {
+				msg += fmt.Sprintf("⊘ Skipped: %d (already in database)\n", result.Skipped)
+			}
+			if unchanged > 0 {
+				msg += fmt.Sprintf("⊘ Unchanged: %d (not re-read since last import)\n", unchanged)
 			}
-			if failed > 0 {
-				msg += fmt.Sprintf("✗ Failed: %d (check logs for details)\n", failed)
+			if result.Failed > 0 {
+				msg += fmt.Sprintf("✗ Failed: %d (check logs for details)\n", result.Failed)
+			}
+			if cancelled {
+				msg += fmt.Sprintf("\nFolders processed: %d of %d", result.Done, len(folders))
+			} else {
+				msg += fmt.Sprintf("\nTotal folders scanned: %d", len(folders)+unchanged)
 			}
-			msg += fmt.Sprintf("\nTotal folders scanned: %d", len(folders))
 
 			dialog.ShowInformation("Import Results", msg, u.window)
 			u.refreshMatches()
@@ -583,6 +774,89 @@ func (u *UI) showImportAllDialog() {
 	}, u.window)
 }
 
+// showReanalyzeDialog recomputes the stats of every match analyzed by an
+// older version of the parser, or of every match if all is set
+func (u *UI) showReanalyzeDialog(all bool) {
+	// Closing the dialog (its Cancel button) stops after the current match
+	ctx, cancel := context.WithCancel(context.Background())
+
+	statusLabel := widget.NewLabel("Looking for outdated matches...")
+	progressBar := widget.NewProgressBar()
+	progressDialog := dialog.NewCustom("Re-analyzing Library", "Cancel",
+		container.NewVBox(statusLabel, progressBar), u.window)
+	progressDialog.SetOnClosed(cancel)
+	progressDialog.Show()
+
+	go func() {
+		defer cancel()
+
+		result, err := u.parser.ReanalyzeLibrary(ctx, all, func(p parser.ReanalyzeProgress) {
+			statusLabel.SetText(fmt.Sprintf("Re-analyzed %d of %d...\n%s", p.Done, p.Total, p.Last.Match.Map))
+			progressBar.SetValue(float64(p.Done) / float64(p.Total))
+		})
+		cancelled := errors.Is(err, context.Canceled)
+
+		progressDialog.Hide()
+
+		if err != nil && !cancelled {
+			dialog.ShowError(err, u.window)
+			return
+		}
+		if result.Total == 0 {
+			dialog.ShowInformation("Re-analyze", "All matches are up to date.", u.window)
+			return
+		}
+
+		msg := "Re-analysis Complete!\n\n"
+		if cancelled {
+			msg = "Re-analysis Cancelled\n\n"
+		}
+		msg += fmt.Sprintf("✓ Updated: %d matches\n", result.Updated)
+		if result.Failed > 0 {
+			msg += fmt.Sprintf("✗ Failed: %d (replay files missing or changed, check logs)\n", result.Failed)
+		}
+		if cancelled {
+			msg += fmt.Sprintf("\nMatches processed: %d of %d", result.Done, result.Total)
+		}
+
+		dialog.ShowInformation("Re-analyze Results", msg, u.window)
+		u.refreshMatches()
+	}()
+}
+
+// showReanalyzeMatchDialog recomputes the stats of a single match. Decoding
+// its replay can take a while, so it runs off the UI thread.
+func (u *UI) showReanalyzeMatchDialog(match models.Match) {
+	// Closing the dialog (its Cancel button) leaves the match untouched
+	ctx, cancel := context.WithCancel(context.Background())
+
+	progressDialog := dialog.NewCustom("Re-analyzing Match", "Cancel",
+		container.NewVBox(widget.NewLabel(fmt.Sprintf("Re-analyzing %s...", match.Map)), widget.NewProgressBarInfinite()), u.window)
+	progressDialog.SetOnClosed(cancel)
+	progressDialog.Show()
+
+	go func() {
+		defer cancel()
+
+		err := u.parser.Reanalyze(ctx, match.ID)
+		cancelled := errors.Is(err, context.Canceled)
+
+		progressDialog.Hide()
+
+		if cancelled {
+			dialog.ShowInformation("Re-analyze", "Re-analysis cancelled. The match was not changed.", u.window)
+			return
+		}
+		if err != nil {
+			log.Printf("ERROR re-analyzing match %d: %v", match.ID, err)
+			dialog.ShowError(err, u.window)
+			return
+		}
+		dialog.ShowInformation("Re-analyze", "Match stats recomputed. Reopen the match to see them.", u.window)
+		u.refreshMatches()
+	}()
+}
+
 func (u *UI) showMatchDetails(match models.Match) {
 	rounds, err := u.db.GetRoundsByMatch(match.ID)
 	if err != nil {
@@ -598,6 +872,7 @@ func (u *UI) showMatchDetails(match models.Match) {
 	for _, s := range allStats {
 		if _, exists := playerAggregates[s.Username]; !exists {
 			playerAggregates[s.Username] = &aggregatedStats{
+				ProfileID: s.ProfileID,
 				Username:  s.Username,
 				TeamIndex: s.TeamIndex,
 			}
@@ -632,6 +907,11 @@ func (u *UI) showMatchDetails(match models.Match) {
 		if s.Survived {
 			agg.Survived++
 		}
+		if s.KOST {
+			agg.KOSTRounds++
+		}
+		agg.SurvivalTime += s.SurvivalTime
+		agg.Rating += s.Rating
 		agg.Rounds++
 	}
 
@@ -650,13 +930,22 @@ func (u *UI) showMatchDetails(match models.Match) {
 		// Separate by team
 		var yourTeam, opponents []*aggregatedStats
 		for _, agg := range playerAggregates {
-			if agg.TeamIndex == 0 {
+			if agg.TeamIndex == match.RecordingTeamIndex {
 				yourTeam = append(yourTeam, agg)
 			} else {
 				opponents = append(opponents, agg)
 			}
 		}
 
+		// Best rated first
+		byRating := func(players []*aggregatedStats) {
+			sort.Slice(players, func(i, j int) bool {
+				return players[i].Rating/float64(players[i].Rounds) > players[j].Rating/float64(players[j].Rounds)
+			})
+		}
+		byRating(yourTeam)
+		byRating(opponents)
+
 		// Your team
 		content.Add(widget.NewLabel("Your Team:"))
 		content.Add(u.buildAggregatedStatsTable(yourTeam))
@@ -701,8 +990,13 @@ func (u *UI) showMatchDetails(match models.Match) {
 			}
 		}, u.window)
 	})
+	// Re-analyze button
+	reanalyzeBtn := widget.NewButtonWithIcon("Re-analyze Match", theme.ViewRefreshIcon(), func() {
+		u.showReanalyzeMatchDialog(match)
+	})
+
 	content.Add(widget.NewSeparator())
-	content.Add(deleteBtn)
+	content.Add(container.NewHBox(reanalyzeBtn, deleteBtn))
 
 	scroll := container.NewVScroll(content)
 	scroll.SetMinSize(fyne.NewSize(800, 650))
@@ -713,35 +1007,41 @@ func (u *UI) showMatchDetails(match models.Match) {
 }
 
 type aggregatedStats struct {
-	Username    string
-	TeamIndex   int
-	Kills       int
-	Deaths      int
-	Assists     int
-	Headshots   int
-	EntryKills  int
-	EntryDeaths int
-	Plants      int
-	Defuses     int
-	DoubleKills int
-	TripleKills int
-	QuadKills   int
-	Aces        int
-	Clutches    int
-	TradeKills  int
-	Survived    int
-	Rounds      int
+	ProfileID    string
+	Username     string
+	TeamIndex    int
+	Kills        int
+	Deaths       int
+	Assists      int
+	Headshots    int
+	EntryKills   int
+	EntryDeaths  int
+	Plants       int
+	Defuses      int
+	DoubleKills  int
+	TripleKills  int
+	QuadKills    int
+	Aces         int
+	Clutches     int
+	TradeKills   int
+	Survived     int
+	KOSTRounds   int
+	SurvivalTime float64
+	Rating       float64 // summed over rounds
+	Rounds       int
 }
 
 func (u *UI) buildAggregatedStatsTable(stats []*aggregatedStats) fyne.CanvasObject {
-	header := container.NewGridWithColumns(15,
+	header := container.NewGridWithColumns(17,
 		widget.NewLabelWithStyle("Player", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Rating", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("K", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("D", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("A", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("K/D", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("HS%", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("KOST", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Surv", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("Entry", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("Plant", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("Defuse", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
@@ -766,20 +1066,26 @@ func (u *UI) buildAggregatedStatsTable(stats []*aggregatedStats) fyne.CanvasObje
 			hsPercent = (float64(s.Headshots) / float64(s.Kills)) * 100
 		}
 
-		// KOST percentage (survived rounds / total rounds)
+		// KOST percentage, average time alive and average rating per round
 		kostPercent := 0.0
+		avgSurvival := 0.0
+		rating := 0.0
 		if s.Rounds > 0 {
-			kostPercent = float64(s.Survived) / float64(s.Rounds) * 100
+			kostPercent = float64(s.KOSTRounds) / float64(s.Rounds) * 100
+			avgSurvival = s.SurvivalTime / float64(s.Rounds)
+			rating = s.Rating / float64(s.Rounds)
 		}
 
-		row := container.NewGridWithColumns(15,
-			widget.NewLabel(s.Username),
+		row := container.NewGridWithColumns(17,
+			u.playerName(s.Username, s.ProfileID),
+			widget.NewLabelWithStyle(fmt.Sprintf("%.2f", rating), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 			widget.NewLabelWithStyle(fmt.Sprintf("%d", s.Kills), fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(fmt.Sprintf("%d", s.Deaths), fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(fmt.Sprintf("%d", s.Assists), fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(fmt.Sprintf("%.2f", kd), fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(fmt.Sprintf("%.0f%%", hsPercent), fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(fmt.Sprintf("%.0f%%", kostPercent), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(fmt.Sprintf("%.0fs", avgSurvival), fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(fmt.Sprintf("%d", s.EntryKills), fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(fmt.Sprintf("%d", s.Plants), fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(fmt.Sprintf("%d", s.Defuses), fyne.TextAlignCenter, fyne.TextStyle{}),
@@ -810,7 +1116,7 @@ func (u *UI) showRoundDetails(round models.Round, match models.Match) {
 		// Separate by team
 		var yourTeamStats, opponentStats []models.PlayerRoundStats
 		for _, s := range playerStats {
-			if s.TeamIndex == 0 {
+			if s.TeamIndex == match.RecordingTeamIndex {
 				yourTeamStats = append(yourTeamStats, s)
 			} else {
 				opponentStats = append(opponentStats, s)
@@ -828,20 +1134,10 @@ func (u *UI) showRoundDetails(round models.Round, match models.Match) {
 		content.Add(widget.NewSeparator())
 	}
 
-	// Kill feed
+	// Timeline of every event in the round
 	if len(events) > 0 {
-		content.Add(widget.NewLabelWithStyle("Kill Feed:", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
-
-		for _, e := range events {
-			if e.EventType == "Kill" {
-				hs := ""
-				if e.Headshot {
-					hs = " [HS]"
-				}
-				eventText := fmt.Sprintf("[%s] %s killed %s%s", e.Time, e.Username, e.Target, hs)
-				content.Add(widget.NewLabel(eventText))
-			}
-		}
+		content.Add(widget.NewLabelWithStyle("Timeline:", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		content.Add(u.buildRoundTimeline(events, playerStats, round, match))
 	}
 
 	scroll := container.NewVScroll(content)
@@ -854,14 +1150,16 @@ func (u *UI) showRoundDetails(round models.Round, match models.Match) {
 
 func (u *UI) buildStatsTable(stats []models.PlayerRoundStats) fyne.CanvasObject {
 	// Header row with HS% and KOST
-	header := container.NewGridWithColumns(15,
+	header := container.NewGridWithColumns(17,
 		widget.NewLabelWithStyle("Player", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Rating", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("Op", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("K", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("D", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("A", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("HS%", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("KOST", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Surv", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("Entry", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("Plant", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabelWithStyle("Defuse", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
@@ -909,18 +1207,20 @@ func (u *UI) buildStatsTable(stats []models.PlayerRoundStats) fyne.CanvasObject
 
 		// KOST indicator - Kill OR Objective OR Survived OR Traded
 		kost := ""
-		if s.Kills > 0 || s.DefuserPlants > 0 || s.DefuserDefuses > 0 || s.Survived || s.TradeKills > 0 {
+		if s.KOST {
 			kost = "!"
 		}
 
-		row := container.NewGridWithColumns(15,
-			widget.NewLabel(s.Username),
+		row := container.NewGridWithColumns(17,
+			u.playerName(s.Username, s.ProfileID),
+			widget.NewLabelWithStyle(fmt.Sprintf("%.2f", s.Rating), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 			widget.NewLabelWithStyle(s.Operator, fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(fmt.Sprintf("%d", s.Kills), fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(died, fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(fmt.Sprintf("%d", s.Assists), fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(hsPercent, fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(kost, fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(fmt.Sprintf("%.0fs", s.SurvivalTime), fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(entry, fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(fmt.Sprintf("%d", s.DefuserPlants), fyne.TextAlignCenter, fyne.TextStyle{}),
 			widget.NewLabelWithStyle(fmt.Sprintf("%d", s.DefuserDefuses), fyne.TextAlignCenter, fyne.TextStyle{}),
@@ -940,7 +1240,10 @@ func (u *UI) buildStatsTable(stats []models.PlayerRoundStats) fyne.CanvasObject
 func (u *UI) StartWatcher(path string) {
 	u.StopWatcher()
 	u.watcher = parser.NewFolderWatcher(path, u.parser, 30*time.Second)
-	u.watcher.Start(func(match *models.Match) {
+	if settings, err := u.db.GetSettings(); err == nil {
+		u.watcher.Poll = settings.WatchPolling
+	}
+	u.watcher.Start(context.Background(), func(match *models.Match) {
 		u.refreshMatches()
 		u.updateMapFilter()
 	})
@@ -959,10 +1262,122 @@ func (u *UI) exportData() {
 		if err != nil || writer == nil {
 			return
 		}
-		defer writer.Close()
 
-		// Would implement JSON export here
-		dialog.ShowInformation("Export", "Data exported successfully!", u.window)
+		go func() {
+			exported, err := u.db.ExportJSON(writer)
+			if closeErr := writer.Close(); err == nil {
+				err = closeErr
+			}
+
+			if err != nil {
+				log.Printf("ERROR exporting data: %v", err)
+				dialog.ShowError(fmt.Errorf("Export failed after %d matches: %v", exported, err), u.window)
+				return
+			}
+
+			dialog.ShowInformation("Export", fmt.Sprintf("Exported %d matches to %s", exported, writer.URI().Name()), u.window)
+		}()
+	}, u.window)
+}
+
+func (u *UI) clearData() {
+	backupCheck := widget.NewCheck("Back up replays.db first", nil)
+	backupCheck.SetChecked(true)
+
+	content := container.NewVBox(
+		widget.NewLabel("Are you sure you want to delete all match data? This cannot be undone."),
+		backupCheck,
+	)
+
+	dialog.ShowCustomConfirm("Clear Data", "Delete", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+
+		// The backup and the VACUUM after deleting can take a while on a
+		// large library, so both run off the UI thread
+		status := widget.NewLabel("Deleting match data...")
+		if backupCheck.Checked {
+			status.SetText("Backing up replays.db...")
+		}
+		progressDialog := dialog.NewCustom("Clearing Data", "Hide", status, u.window)
+		progressDialog.Show()
+
+		backup := backupCheck.Checked
+		go func() {
+			msg := "All data has been cleared."
+			if backup {
+				backupPath, err := u.db.Backup()
+				if err != nil {
+					progressDialog.Hide()
+					log.Printf("ERROR backing up database: %v", err)
+					dialog.ShowError(fmt.Errorf("Backup failed, nothing was deleted: %v", err), u.window)
+					return
+				}
+				msg += fmt.Sprintf("\n\nBackup saved to:\n%s", backupPath)
+				status.SetText("Deleting match data...")
+			}
+
+			err := u.db.ClearAllMatches()
+			progressDialog.Hide()
+			if err != nil {
+				log.Printf("ERROR clearing database: %v", err)
+				dialog.ShowError(err, u.window)
+				return
+			}
+
+			u.refreshMatches()
+			u.updateMapFilter()
+			dialog.ShowInformation("Cleared", msg, u.window)
+		}()
+	}, u.window)
+}
+
+func (u *UI) importData() {
+	dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+
+		progressDialog := dialog.NewCustom("Importing", "Hide",
+			widget.NewLabel("Merging archive into library..."), u.window)
+		progressDialog.Show()
+
+		go func() {
+			defer reader.Close()
+
+			summary, err := u.db.ImportJSON(reader)
+			progressDialog.Hide()
+
+			if err != nil {
+				log.Printf("ERROR importing archive: %v", err)
+				msg := fmt.Sprintf("%v", err)
+				if summary != nil && summary.MatchesAdded > 0 {
+					msg += fmt.Sprintf("\n\n%d matches were imported before the error.", summary.MatchesAdded)
+				}
+				dialog.ShowError(fmt.Errorf("%s", msg), u.window)
+				u.refreshMatches()
+				u.updateMapFilter()
+				return
+			}
+
+			msg := "Archive Import Complete!\n\n"
+			msg += fmt.Sprintf("✓ Added: %d matches (%d rounds, %d players, %d events, %d stat rows)\n",
+				summary.MatchesAdded, summary.RoundsAdded, summary.PlayersAdded, summary.EventsAdded, summary.StatsAdded)
+			if summary.MatchesSkipped > 0 {
+				msg += fmt.Sprintf("⊘ Skipped: %d (already in database)\n", summary.MatchesSkipped)
+			}
+			if summary.MatchesConflicting > 0 {
+				msg += fmt.Sprintf("⚠ Conflicts: %d (same match ID, different data - local copy kept)\n", summary.MatchesConflicting)
+			}
+			if missing := summary.RoundsAdded - summary.RawRoundsAdded; missing > 0 {
+				msg += fmt.Sprintf("ⓘ %d rounds came without their replay data and can only be re-analyzed from their .rec files\n", missing)
+			}
+
+			dialog.ShowInformation("Import Results", msg, u.window)
+			u.refreshMatches()
+			u.updateMapFilter()
+		}()
 	}, u.window)
 }
 
@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// winConditionLabels are readable names for the stored round win conditions
+var winConditionLabels = map[string]string{
+	"KilledOpponents": "Eliminations",
+	"SecuredArea":     "Secured Area",
+	"DisabledDefuser": "Defuser Disabled",
+	"DefusedBomb":     "Defuser Planted",
+	"Time":            "Time",
+}
+
+func winConditionLabel(wc string) string {
+	if label, ok := winConditionLabels[wc]; ok {
+		return label
+	}
+	return wc
+}
+
+// buildWinConditionCard returns the win condition breakdown and the weekly
+// trend of rounds lost to time or to a plant, for the selected stats side.
+// Its map filter is kept across stats refreshes.
+func (u *UI) buildWinConditionCard() fyne.CanvasObject {
+	body := container.NewVBox()
+	refresh := func() {
+		body.Objects = []fyne.CanvasObject{
+			u.buildWinConditionTable(),
+			widget.NewSeparator(),
+			widget.NewLabelWithStyle("Rounds Lost to Time or Plant, by Week", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			u.buildWinConditionTrend(),
+		}
+		body.Refresh()
+	}
+
+	maps, _ := u.db.GetDistinctMaps()
+	mapSelect := widget.NewSelect(append([]string{"All"}, maps...), nil)
+	mapSelect.SetSelected(selectedOrAll(u.winConditionMap))
+	mapSelect.OnChanged = func(s string) {
+		u.winConditionMap = s
+		refresh()
+	}
+
+	refresh()
+	return widget.NewCard("Win Conditions", "How your rounds are won and lost",
+		container.NewVBox(container.NewHBox(widget.NewLabel("Map:"), mapSelect), body))
+}
+
+func (u *UI) buildWinConditionTable() fyne.CanvasObject {
+	stats, err := u.db.GetWinConditionStats(u.winConditionMap, u.statsSide)
+	if err != nil {
+		return widget.NewLabel(fmt.Sprintf("Error loading win conditions: %v", err))
+	}
+	if len(stats) == 0 {
+		return widget.NewLabel("No rounds match these filters")
+	}
+
+	rows := []fyne.CanvasObject{
+		container.NewGridWithColumns(4,
+			widget.NewLabelWithStyle("Side", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Condition", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Won", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Lost", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		),
+	}
+
+	for _, s := range stats {
+		rows = append(rows, container.NewGridWithColumns(4,
+			widget.NewLabel(s.Side),
+			widget.NewLabel(winConditionLabel(s.WinCondition)),
+			widget.NewLabelWithStyle(fmt.Sprintf("%d (%.0f%%)", s.Won, s.WonShare), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(fmt.Sprintf("%d (%.0f%%)", s.Lost, s.LostShare), fyne.TextAlignCenter, fyne.TextStyle{}),
+		))
+	}
+
+	return container.NewVBox(rows...)
+}
+
+func (u *UI) buildWinConditionTrend() fyne.CanvasObject {
+	trend, err := u.db.GetWinConditionTrend(u.winConditionMap, u.statsSide)
+	if err != nil {
+		return widget.NewLabel(fmt.Sprintf("Error loading trend: %v", err))
+	}
+	if len(trend) == 0 {
+		return widget.NewLabel("No rounds match these filters")
+	}
+
+	rows := []fyne.CanvasObject{
+		container.NewGridWithColumns(5,
+			widget.NewLabelWithStyle("Week", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Rounds", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("Lost", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("To Time", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			widget.NewLabelWithStyle("To Plant", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		),
+	}
+
+	for _, t := range trend {
+		rows = append(rows, container.NewGridWithColumns(5,
+			widget.NewLabel(t.Week.Format("2006-01-02")),
+			widget.NewLabelWithStyle(fmt.Sprintf("%d", t.Rounds), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(fmt.Sprintf("%d", t.Lost), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(fmt.Sprintf("%d (%.0f%%)", t.LostToTime, t.TimeShare), fyne.TextAlignCenter, fyne.TextStyle{}),
+			widget.NewLabelWithStyle(fmt.Sprintf("%d (%.0f%%)", t.LostToPlant, t.PlantShare), fyne.TextAlignCenter, fyne.TextStyle{}),
+		))
+	}
+
+	return container.NewVBox(rows...)
+}